@@ -0,0 +1,53 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotExist             = errors.New("object does not exist")
+	ErrInvalidKey           = errors.New("invalid object key")
+	ErrSignedURLUnsupported = errors.New("signed urls are not configured for this store")
+	ErrInvalidSignature     = errors.New("invalid or expired signature")
+)
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	ContentType  string
+	LastModified time.Time
+	Metadata     map[string]string
+}
+
+type PutOptions struct {
+	ContentType string
+	Metadata    map[string]string
+
+	// PartSize is the size of the parts a large or unsized upload is split
+	// into. Only one part is buffered at a time, so it bounds the memory used
+	// by the upload.
+	PartSize uint64
+}
+
+type GetOptions struct {
+	// Offset and Length select a byte range of the object. A zero Length
+	// reads until the end.
+	Offset int64
+	Length int64
+}
+
+// BlobStore is the object storage both services keep playlist audio in.
+type BlobStore interface {
+	// Put stores r under key. size may be -1 when it is not known up front.
+	Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error
+	Get(ctx context.Context, key string, opts GetOptions) (io.ReadSeekCloser, ObjectInfo, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// List returns every object below prefix, recursively.
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
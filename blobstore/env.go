@@ -0,0 +1,36 @@
+package blobstore
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// NewFromEnv creates the store selected by BLOB_STORE: "minio" (the default)
+// or "local".
+func NewFromEnv() (BlobStore, error) {
+	switch backend := os.Getenv("BLOB_STORE"); backend {
+	case "", "minio":
+		client, err := minio.New(os.Getenv("MINIO_SERVER_ADDR"), &minio.Options{
+			Creds: credentials.NewStaticV4(os.Getenv("MINIO_ACCESS_KEY"), os.Getenv("MINIO_SECRET_KEY"), ""),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return NewMinioStore(client, os.Getenv("MINIO_BUCKET_NAME")), nil
+
+	case "local":
+		dir := os.Getenv("BLOB_STORE_DIR")
+		if dir == "" {
+			return nil, fmt.Errorf("BLOB_STORE_DIR is required for the local blob store")
+		}
+
+		return NewLocalStore(dir, os.Getenv("BLOB_STORE_URL"), []byte(os.Getenv("BLOB_STORE_SECRET")))
+
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q", backend)
+	}
+}
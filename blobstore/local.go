@@ -0,0 +1,329 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	localMetaDir    = ".meta"
+	localTempPrefix = ".tmp-"
+)
+
+type localMeta struct {
+	ContentType string            `json:"content_type,omitempty"`
+	ETag        string            `json:"etag,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// LocalStore keeps objects as plain files below a directory, for small self
+// hosted installs and tests that run without MinIO. Content type, ETag and
+// user metadata live in a JSON file per object under .meta.
+//
+// Signed URLs point at baseURL and are verified by ServeHTTP, so baseURL has
+// to be where the store is mounted.
+type LocalStore struct {
+	dir     string
+	baseURL string
+	secret  []byte
+}
+
+func NewLocalStore(dir, baseURL string, secret []byte) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  secret,
+	}, nil
+}
+
+func (ls *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", ErrInvalidKey
+	}
+
+	if first := strings.SplitN(key, "/", 2)[0]; first == localMetaDir {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(ls.dir, filepath.FromSlash(key)), nil
+}
+
+func (ls *LocalStore) metaPath(key string) string {
+	return filepath.Join(ls.dir, localMetaDir, filepath.FromSlash(key)+".json")
+}
+
+func (ls *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error {
+	objPath, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objPath), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(objPath), localTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), r); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), objPath); err != nil {
+		return err
+	}
+
+	return ls.writeMeta(key, localMeta{
+		ContentType: opts.ContentType,
+		ETag:        hex.EncodeToString(hash.Sum(nil)),
+		Metadata:    opts.Metadata,
+	})
+}
+
+func (ls *LocalStore) writeMeta(key string, meta localMeta) error {
+	metaPath := ls.metaPath(key)
+
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(metaPath, data, 0o644)
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (s sectionReadCloser) Close() error {
+	return s.file.Close()
+}
+
+func (ls *LocalStore) Get(ctx context.Context, key string, opts GetOptions) (io.ReadSeekCloser, ObjectInfo, error) {
+	info, err := ls.Stat(ctx, key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	objPath, _ := ls.path(key)
+
+	file, err := os.Open(objPath)
+	if err != nil {
+		return nil, ObjectInfo{}, convertFSErr(err)
+	}
+
+	if opts.Offset == 0 && opts.Length == 0 {
+		return file, info, nil
+	}
+
+	length := opts.Length
+	if length == 0 || opts.Offset+length > info.Size {
+		length = info.Size - opts.Offset
+	}
+
+	return sectionReadCloser{
+		SectionReader: io.NewSectionReader(file, opts.Offset, length),
+		file:          file,
+	}, info, nil
+}
+
+func (ls *LocalStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	objPath, err := ls.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	fileInfo, err := os.Stat(objPath)
+	if err != nil {
+		return ObjectInfo{}, convertFSErr(err)
+	}
+
+	if fileInfo.IsDir() {
+		return ObjectInfo{}, ErrNotExist
+	}
+
+	meta := localMeta{}
+	if data, err := os.ReadFile(ls.metaPath(key)); err == nil {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+
+	if meta.ContentType == "" {
+		meta.ContentType = mime.TypeByExtension(path.Ext(key))
+	}
+
+	if meta.ETag == "" {
+		meta.ETag = fmt.Sprintf("%x-%x", fileInfo.ModTime().UnixNano(), fileInfo.Size())
+	}
+
+	return ObjectInfo{
+		Key:          key,
+		Size:         fileInfo.Size(),
+		ETag:         meta.ETag,
+		ContentType:  meta.ContentType,
+		LastModified: fileInfo.ModTime(),
+		Metadata:     meta.Metadata,
+	}, nil
+}
+
+func (ls *LocalStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+
+	err := filepath.WalkDir(ls.dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == localMetaDir && filepath.Dir(filePath) == filepath.Clean(ls.dir) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if strings.HasPrefix(d.Name(), localTempPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ls.dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := ls.Stat(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, info)
+		return nil
+	})
+
+	return objects, err
+}
+
+func (ls *LocalStore) Delete(ctx context.Context, key string) error {
+	objPath, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(objPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Remove(ls.metaPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (ls *LocalStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if ls.baseURL == "" || len(ls.secret) == 0 {
+		return "", ErrSignedURLUnsupported
+	}
+
+	if _, err := ls.path(key); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", ls.sign(key, expires))
+
+	return fmt.Sprintf("%s/%s?%s", ls.baseURL, (&url.URL{Path: key}).EscapedPath(), query.Encode()), nil
+}
+
+func (ls *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, ls.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves objects requested through signed URLs. The request path,
+// with the mount prefix stripped, is the object key.
+func (ls *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	expires := r.URL.Query().Get("expires")
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if len(ls.secret) == 0 || err != nil || time.Now().Unix() > expiresAt ||
+		!hmac.Equal([]byte(ls.sign(key, expires)), []byte(r.URL.Query().Get("signature"))) {
+		http.Error(w, ErrInvalidSignature.Error(), http.StatusForbidden)
+		return
+	}
+
+	obj, info, err := ls.Get(r.Context(), key, GetOptions{})
+	if err != nil {
+		if errors.Is(err, ErrNotExist) || errors.Is(err, ErrInvalidKey) {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	defer obj.Close()
+
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	w.Header().Set("ETag", fmt.Sprintf("%q", info.ETag))
+
+	http.ServeContent(w, r, path.Base(key), info.LastModified, obj)
+}
+
+func convertFSErr(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotExist
+	}
+
+	return err
+}
@@ -0,0 +1,123 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+type minioStore struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewMinioStore(client *minio.Client, bucket string) BlobStore {
+	return &minioStore{
+		client: client,
+		bucket: bucket,
+	}
+}
+
+func (ms *minioStore) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error {
+	putOpts := minio.PutObjectOptions{
+		ContentType:  opts.ContentType,
+		UserMetadata: opts.Metadata,
+		PartSize:     opts.PartSize,
+	}
+
+	// upload one part at a time so that PartSize bounds the memory
+	if opts.PartSize > 0 {
+		putOpts.NumThreads = 1
+	}
+
+	_, err := ms.client.PutObject(ctx, ms.bucket, key, r, size, putOpts)
+	return err
+}
+
+func (ms *minioStore) Get(ctx context.Context, key string, opts GetOptions) (io.ReadSeekCloser, ObjectInfo, error) {
+	getOpts := minio.GetObjectOptions{}
+
+	if opts.Offset > 0 || opts.Length > 0 {
+		end := int64(0)
+		if opts.Length > 0 {
+			end = opts.Offset + opts.Length - 1
+		}
+
+		if err := getOpts.SetRange(opts.Offset, end); err != nil {
+			return nil, ObjectInfo{}, err
+		}
+	}
+
+	obj, err := ms.client.GetObject(ctx, ms.bucket, key, getOpts)
+	if err != nil {
+		return nil, ObjectInfo{}, convertMinioErr(err)
+	}
+
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, ObjectInfo{}, convertMinioErr(err)
+	}
+
+	return obj, toObjectInfo(info), nil
+}
+
+func (ms *minioStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := ms.client.StatObject(ctx, ms.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, convertMinioErr(err)
+	}
+
+	return toObjectInfo(info), nil
+}
+
+func (ms *minioStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+
+	for object := range ms.client.ListObjects(ctx, ms.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+
+		objects = append(objects, toObjectInfo(object))
+	}
+
+	return objects, nil
+}
+
+func (ms *minioStore) Delete(ctx context.Context, key string) error {
+	return ms.client.RemoveObject(ctx, ms.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (ms *minioStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	url, err := ms.client.PresignedGetObject(ctx, ms.bucket, key, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return url.String(), nil
+}
+
+func toObjectInfo(info minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+		Metadata:     info.UserMetadata,
+	}
+}
+
+func convertMinioErr(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotExist
+	}
+
+	return err
+}
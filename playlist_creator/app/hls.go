@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/NikhilSharmaWe/playree/blobstore"
+)
+
+const hlsMasterPlaylist = "master.m3u8"
+
+var hlsContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+}
+
+// hlsPrefix is where the HLS rendition of a track is stored, next to the
+// track object itself.
+func hlsPrefix(trackKey string) string {
+	return strings.TrimSuffix(trackKey, path.Ext(trackKey)) + "/hls"
+}
+
+// packageHLS segments the audio file at inputPath into one AAC rendition per
+// configured bitrate, uploads the segments, the variant playlists and a
+// master playlist, and returns the key of the master playlist.
+func (app *Application) packageHLS(ctx context.Context, inputPath, trackKey string) (string, error) {
+	outputDir := inputPath + ".hls"
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return "", err
+	}
+
+	defer os.RemoveAll(outputDir)
+
+	args := []string{"-hide_banner", "-y", "-i", inputPath}
+	streamMap := []string{}
+
+	for i := range app.HLSBitrates {
+		args = append(args, "-map", "0:a")
+		streamMap = append(streamMap, fmt.Sprintf("a:%d", i))
+	}
+
+	args = append(args, "-c:a", "aac")
+	for i, bitrate := range app.HLSBitrates {
+		args = append(args, fmt.Sprintf("-b:a:%d", i), bitrate)
+	}
+
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", "6",
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(outputDir, "v%v", "seg%03d.ts"),
+		"-master_pl_name", hlsMasterPlaylist,
+		"-var_stream_map", strings.Join(streamMap, " "),
+		filepath.Join(outputDir, "v%v", "index.m3u8"),
+	)
+
+	if out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("ffmpeg hls %s: %w: %s", inputPath, err, out)
+	}
+
+	prefix := hlsPrefix(trackKey)
+
+	if err := filepath.WalkDir(outputDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(outputDir, filePath)
+		if err != nil {
+			return err
+		}
+
+		return app.uploadFile(ctx, prefix+"/"+filepath.ToSlash(rel), filePath, blobstore.PutOptions{
+			ContentType: hlsContentTypes[filepath.Ext(filePath)],
+		})
+	}); err != nil {
+		return "", err
+	}
+
+	return prefix + "/" + hlsMasterPlaylist, nil
+}
+
+// storedSize returns the size of a track's audio object and the number of
+// bytes the track takes up in the blob store, including its HLS rendition.
+// playree uses the first for enclosures and the second for storage quotas.
+func (app *Application) storedSize(ctx context.Context, track *TrackCheckpoint) (int64, int64, error) {
+	info, err := app.BlobStore.Stat(ctx, track.TrackKey)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	size := info.Size
+
+	if track.HLSKey != "" {
+		objects, err := app.BlobStore.List(ctx, path.Dir(track.HLSKey)+"/")
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for _, object := range objects {
+			size += object.Size
+		}
+	}
+
+	return info.Size, size, nil
+}
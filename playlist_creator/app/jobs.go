@@ -0,0 +1,149 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/blobstore"
+	"github.com/NikhilSharmaWe/playree/playlist_creator/proto"
+)
+
+type TrackState string
+
+const (
+	TrackPending    TrackState = ""
+	TrackResolved   TrackState = "resolved"
+	TrackSkipped    TrackState = "skipped"
+	TrackDownloaded TrackState = "downloaded"
+	TrackUploaded   TrackState = "uploaded"
+)
+
+// JobCheckpoint is the persisted progress of a create playlist job. It has one
+// entry per requested track, in request order.
+type JobCheckpoint struct {
+	JobID             string             `json:"job_id"`
+	PlayreePlaylistID string             `json:"playree_playlist_id"`
+	Tracks            []*TrackCheckpoint `json:"tracks"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
+
+type TrackCheckpoint struct {
+	Position       int32             `json:"position"`
+	TrackID        string            `json:"track_id,omitempty"`
+	TrackKey       string            `json:"track_key,omitempty"`
+	VideoID        string            `json:"video_id,omitempty"`
+	HLSKey         string            `json:"hls_key,omitempty"`
+	SizeBytes      int64             `json:"size_bytes,omitempty"`
+	AudioSizeBytes int64             `json:"audio_size_bytes,omitempty"`
+	State          TrackState        `json:"state,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+func newJobCheckpoint(req CreatePlaylistRequest) *JobCheckpoint {
+	cp := &JobCheckpoint{
+		JobID:             req.ID(),
+		PlayreePlaylistID: req.PlayreePlaylistID,
+	}
+
+	for i := range req.Tracks {
+		cp.Tracks = append(cp.Tracks, &TrackCheckpoint{Position: int32(i)})
+	}
+
+	return cp
+}
+
+// CreatedTracks returns the tracks that made it to object storage.
+func (cp *JobCheckpoint) CreatedTracks() []*proto.CreatedTrack {
+	created := []*proto.CreatedTrack{}
+
+	for _, track := range cp.Tracks {
+		if track.State != TrackUploaded {
+			continue
+		}
+
+		created = append(created, &proto.CreatedTrack{
+			Position:       track.Position,
+			TrackId:        track.TrackID,
+			TrackKey:       track.TrackKey,
+			VideoId:        track.VideoID,
+			HlsKey:         track.HLSKey,
+			SizeBytes:      track.SizeBytes,
+			AudioSizeBytes: track.AudioSizeBytes,
+		})
+	}
+
+	return created
+}
+
+type JobStore interface {
+	Get(ctx context.Context, jobID string) (*JobCheckpoint, error)
+	Save(ctx context.Context, cp *JobCheckpoint) error
+	Delete(ctx context.Context, jobID string) error
+}
+
+type jobStore struct {
+	blobStore blobstore.BlobStore
+	prefix    string
+}
+
+// NewJobStore keeps job checkpoints as JSON objects under prefix, so any
+// instance that picks up a redelivered job can resume it.
+func NewJobStore(blobStore blobstore.BlobStore, prefix string) JobStore {
+	return &jobStore{
+		blobStore: blobStore,
+		prefix:    prefix,
+	}
+}
+
+func (js *jobStore) key(jobID string) string {
+	return fmt.Sprintf("%s/%s.json", js.prefix, jobID)
+}
+
+func (js *jobStore) Get(ctx context.Context, jobID string) (*JobCheckpoint, error) {
+	obj, _, err := js.blobStore.Get(ctx, js.key(jobID), blobstore.GetOptions{})
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotExist) {
+			return nil, nil // checkpoint not found
+		}
+
+		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+
+	defer obj.Close()
+
+	var cp JobCheckpoint
+	if err := json.NewDecoder(obj).Decode(&cp); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+
+	return &cp, nil
+}
+
+func (js *jobStore) Save(ctx context.Context, cp *JobCheckpoint) error {
+	cp.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	if err := js.blobStore.Put(ctx, js.key(cp.JobID), bytes.NewReader(data), int64(len(data)), blobstore.PutOptions{
+		ContentType: "application/json",
+	}); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (js *jobStore) Delete(ctx context.Context, jobID string) error {
+	if err := js.blobStore.Delete(ctx, js.key(jobID)); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+
+	return nil
+}
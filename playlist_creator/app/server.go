@@ -34,14 +34,18 @@ func NewCreatePlaylistServer(app *Application) *CreatePlaylistServer {
 }
 
 func (s *CreatePlaylistServer) CreatePlaylist(ctx context.Context, req *proto.CreatePlaylistRequest) (*proto.CreatePlaylistResponse, error) {
-	if err := s.svc.CreatePlaylist(CreatePlaylistRequest{
+	tracks, err := s.svc.CreatePlaylist(ctx, CreatePlaylistRequest{
+		JobID:             req.JobId,
 		PlayreePlaylistID: req.PlayreePlaylistId,
 		Tracks:            req.Tracks,
-	}); err != nil {
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &proto.CreatePlaylistResponse{
+		JobId:             req.JobId,
 		PlayreePlaylistId: req.PlayreePlaylistId,
+		Tracks:            tracks,
 	}, nil
 }
@@ -1,12 +1,19 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/blobstore"
+	"github.com/NikhilSharmaWe/playree/playlist_creator/proto"
+	"github.com/google/uuid"
 )
 
 type CreatePlaylistService interface {
-	CreatePlaylist(CreatePlaylistRequest) error
+	CreatePlaylist(context.Context, CreatePlaylistRequest) ([]*proto.CreatedTrack, error)
 }
 
 type createPlaylistService struct {
@@ -19,17 +26,113 @@ func NewCreatePlaylistService(app *Application) CreatePlaylistService {
 	}
 }
 
-func (svc *createPlaylistService) CreatePlaylist(req CreatePlaylistRequest) error {
-	videoIDs, err := svc.app.getYTVideoIDs(req.Tracks)
+// CreatePlaylist resolves, downloads and uploads every track of the request.
+// The request either creates a playlist or adds tracks to an existing one;
+// both are jobs identified by their job id. Progress is checkpointed after
+// every step, so a retried or redelivered job skips the tracks that are
+// already done.
+func (svc *createPlaylistService) CreatePlaylist(ctx context.Context, req CreatePlaylistRequest) ([]*proto.CreatedTrack, error) {
+	cp, err := svc.app.JobStore.Get(ctx, req.ID())
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// a checkpoint for a different list of tracks can not be resumed
+	if cp == nil || len(cp.Tracks) != len(req.Tracks) {
+		cp = newJobCheckpoint(req)
+	} else {
+		log.Printf("RESUMING JOB: %s", req.ID())
+	}
+
+	for _, track := range cp.Tracks {
+		if err := svc.processTrack(ctx, req, cp, track); err != nil {
+			return nil, err
+		}
+	}
+
+	if svc.app.spoolToDisk() {
+		os.RemoveAll(svc.app.jobDir(req.ID()))
+	}
+
+	if err := svc.app.JobStore.Delete(ctx, req.ID()); err != nil {
+		log.Println("ERROR: ", err)
+	}
+
+	return cp.CreatedTracks(), nil
+}
+
+func (svc *createPlaylistService) processTrack(ctx context.Context, req CreatePlaylistRequest, cp *JobCheckpoint, track *TrackCheckpoint) error {
+	if track.State == TrackPending {
+		videoID, err := svc.app.resolveTrack(req.Tracks[track.Position])
+		if err != nil {
+			return err
+		}
+
+		if videoID == "" {
+			track.State = TrackSkipped
+		} else {
+			track.TrackID = uuid.NewString()
+			track.TrackKey = fmt.Sprintf("%s/%s.mp3", req.PlayreePlaylistID, track.TrackID)
+			track.VideoID = videoID
+			track.State = TrackResolved
+		}
+
+		if err := svc.app.JobStore.Save(ctx, cp); err != nil {
+			return err
+		}
+	}
+
+	if track.State == TrackSkipped || track.State == TrackUploaded {
+		return nil
+	}
+
+	if svc.app.spoolToDisk() {
+		outputPath := svc.app.trackPath(req.ID(), track.TrackKey)
+
+		if track.State != TrackDownloaded || !fileExists(outputPath) {
+			expected := time.Duration(req.Tracks[track.Position].DurationMs) * time.Millisecond
+
+			metadata, err := svc.app.downloadTrack(ctx, outputPath, track.VideoID, expected)
+			if err != nil {
+				return err
+			}
+
+			track.Metadata = metadata
+			track.State = TrackDownloaded
+
+			if err := svc.app.JobStore.Save(ctx, cp); err != nil {
+				return err
+			}
+		}
+
+		if svc.app.HLSEnabled {
+			hlsKey, err := svc.app.packageHLS(ctx, outputPath, track.TrackKey)
+			if err != nil {
+				return err
+			}
+
+			track.HLSKey = hlsKey
+		}
+
+		if err := svc.app.uploadFile(ctx, track.TrackKey, outputPath, blobstore.PutOptions{
+			Metadata: track.Metadata,
+		}); err != nil {
+			return err
+		}
+	} else {
+		if err := svc.app.streamTrack(ctx, track.TrackKey, track.VideoID); err != nil {
+			return err
+		}
 	}
 
-	if err := svc.app.downloadToAudioLocally(req, videoIDs); err != nil {
+	audioSize, size, err := svc.app.storedSize(ctx, track)
+	if err != nil {
 		return err
 	}
 
-	defer os.RemoveAll(fmt.Sprintf("./local-playlists/%s", req.PlayreePlaylistID))
+	track.AudioSizeBytes = audioSize
+	track.SizeBytes = size
+	track.State = TrackUploaded
 
-	return svc.app.pushToMinio(req.PlayreePlaylistID)
+	return svc.app.JobStore.Save(ctx, cp)
 }
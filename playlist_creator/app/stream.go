@@ -0,0 +1,153 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/NikhilSharmaWe/playree/blobstore"
+	"github.com/NikhilSharmaWe/youtube"
+	ytdl "github.com/NikhilSharmaWe/youtube/downloader"
+)
+
+var errNoAudioFormat = errors.New("no audio format found")
+
+// streamTrack downloads the audio of a video and writes it straight to the
+// blob store without touching the local disk. Memory use is bounded by
+// UploadPartSize.
+func (app *Application) streamTrack(ctx context.Context, key, videoID string) error {
+	downloader := ytdl.GetDownloader("")
+
+	video, err := downloader.GetVideoContext(ctx, videoID)
+	if err != nil {
+		return err
+	}
+
+	format, err := audioFormat(video)
+	if err != nil {
+		return err
+	}
+
+	stream, size, err := downloader.GetStreamContext(ctx, video, format)
+	if err != nil {
+		return err
+	}
+
+	defer stream.Close()
+
+	var (
+		body        io.Reader = stream
+		contentType           = format.MimeType
+		transcoded  *transcodeCmd
+	)
+
+	if app.TranscodeAudio {
+		transcoded, err = transcodeToMP3(ctx, stream)
+		if err != nil {
+			return err
+		}
+
+		defer transcoded.Close()
+
+		body, size, contentType = transcoded, -1, "audio/mpeg"
+	}
+
+	if err := app.BlobStore.Put(ctx, key, body, size, blobstore.PutOptions{
+		ContentType: contentType,
+		PartSize:    app.UploadPartSize,
+	}); err != nil {
+		return err
+	}
+
+	if transcoded != nil {
+		return transcoded.Close()
+	}
+
+	return nil
+}
+
+func audioFormat(video *youtube.Video) (*youtube.Format, error) {
+	formats := video.Formats.Type("audio")
+	if len(formats) == 0 {
+		return nil, errNoAudioFormat
+	}
+
+	formats.Sort()
+
+	return &formats[0], nil
+}
+
+// transcodeCmd pipes audio through ffmpeg. Close waits for ffmpeg to exit and
+// reports its error, if any.
+type transcodeCmd struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+	done   bool
+	err    error
+}
+
+func transcodeToMP3(ctx context.Context, r io.Reader) (*transcodeCmd, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner", "-nostats",
+		"-i", "pipe:0",
+		"-vn", "-codec:a", "libmp3lame", "-q:a", "2",
+		"-f", "mp3", "pipe:1",
+	)
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &transcodeCmd{
+		ReadCloser: stdout,
+		cmd:        cmd,
+		stderr:     &stderr,
+	}, nil
+}
+
+func (t *transcodeCmd) Close() error {
+	if t.done {
+		return t.err
+	}
+
+	t.done = true
+	t.ReadCloser.Close()
+
+	if err := t.cmd.Wait(); err != nil {
+		t.err = fmt.Errorf("ffmpeg transcode: %w: %s", err, t.stderr.String())
+	}
+
+	return t.err
+}
+
+// transcodeFile re-encodes a spooled download to MP3 in place.
+func transcodeFile(ctx context.Context, path string) error {
+	tmpPath := path + ".transcode.mp3"
+
+	out, err := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner", "-y",
+		"-i", path,
+		"-vn", "-codec:a", "libmp3lame", "-q:a", "2",
+		tmpPath,
+	).CombinedOutput()
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("ffmpeg transcode %s: %w: %s", path, err, out)
+	}
+
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,239 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// edgeTolerance is how close a silence has to be to the beginning or the end
+// of the file to be treated as leading or trailing silence.
+const edgeTolerance = 50 * time.Millisecond
+
+var (
+	silenceStartRegexp = regexp.MustCompile(`silence_start: (-?[0-9.]+)`)
+	silenceEndRegexp   = regexp.MustCompile(`silence_end: (-?[0-9.]+)`)
+)
+
+type silence struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// TrimResult records what the trim step did to a downloaded track so the
+// change can be audited later. Start and End are offsets into the original
+// audio.
+type TrimResult struct {
+	OriginalDuration time.Duration
+	Start            time.Duration
+	End              time.Duration
+}
+
+func (r TrimResult) Trimmed() bool {
+	return r.Start > 0 || r.End < r.OriginalDuration
+}
+
+// Metadata returns the trim result as object storage user metadata.
+func (r TrimResult) Metadata() map[string]string {
+	return map[string]string{
+		"Original-Duration-Ms": strconv.FormatInt(r.OriginalDuration.Milliseconds(), 10),
+		"Trim-Start-Ms":        strconv.FormatInt(r.Start.Milliseconds(), 10),
+		"Trim-End-Ms":          strconv.FormatInt(r.End.Milliseconds(), 10),
+	}
+}
+
+// trimAudio removes leading and trailing silence from the audio file at path.
+// When expected is known and the remaining audio is still much longer, it
+// also cuts spoken intros and outros at the silence gaps that bring the
+// duration closest to expected. The file is replaced in place.
+func (app *Application) trimAudio(ctx context.Context, path string, expected time.Duration) (*TrimResult, error) {
+	total, err := probeDuration(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	silences, err := detectSilences(ctx, path, app.TrimSilenceThreshold, app.TrimMinSilence, total)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := cutPoints(silences, total, expected, app.TrimDurationTolerance)
+
+	result := &TrimResult{
+		OriginalDuration: total,
+		Start:            start,
+		End:              end,
+	}
+
+	if !result.Trimmed() {
+		return result, nil
+	}
+
+	if err := cutAudio(ctx, path, start, end); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func cutPoints(silences []silence, total, expected, tolerance time.Duration) (time.Duration, time.Duration) {
+	start, end := time.Duration(0), total
+
+	if len(silences) > 0 {
+		if first := silences[0]; first.Start <= edgeTolerance {
+			start = first.End
+		}
+
+		if last := silences[len(silences)-1]; last.End >= total-edgeTolerance && last.Start > start {
+			end = last.Start
+		}
+	}
+
+	// nothing but silence, leave the file alone
+	if end <= start {
+		return 0, total
+	}
+
+	if expected <= 0 || end-start-expected <= tolerance {
+		return start, end
+	}
+
+	excess := end - start - expected
+
+	starts := []time.Duration{start}
+	ends := []time.Duration{end}
+
+	for _, s := range silences {
+		if s.End > start && s.End <= start+excess+tolerance {
+			starts = append(starts, s.End)
+		}
+
+		if s.Start < end && s.Start >= end-excess-tolerance {
+			ends = append(ends, s.Start)
+		}
+	}
+
+	bestStart, bestEnd := start, end
+	bestDiff := time.Duration(math.MaxInt64)
+
+	for _, s := range starts {
+		for _, e := range ends {
+			if e <= s {
+				continue
+			}
+
+			diff := e - s - expected
+			if diff < 0 {
+				diff = -diff
+			}
+
+			if diff < bestDiff {
+				bestStart, bestEnd, bestDiff = s, e, diff
+			}
+		}
+	}
+
+	return bestStart, bestEnd
+}
+
+func probeDuration(ctx context.Context, path string) (time.Duration, error) {
+	out, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe %s: %w", path, err)
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe %s: %w", path, err)
+	}
+
+	return secondsToDuration(seconds), nil
+}
+
+func detectSilences(ctx context.Context, path, threshold string, minSilence, total time.Duration) ([]silence, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner", "-nostats",
+		"-i", path,
+		"-af", fmt.Sprintf("silencedetect=noise=%s:d=%.3f", threshold, minSilence.Seconds()),
+		"-f", "null", "-",
+	)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg silencedetect %s: %w", path, err)
+	}
+
+	silences := []silence{}
+	var current *silence
+
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := silenceStartRegexp.FindStringSubmatch(line); m != nil {
+			seconds, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				return nil, err
+			}
+
+			current = &silence{Start: max(secondsToDuration(seconds), 0), End: total}
+			continue
+		}
+
+		if m := silenceEndRegexp.FindStringSubmatch(line); m != nil && current != nil {
+			seconds, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				return nil, err
+			}
+
+			current.End = min(secondsToDuration(seconds), total)
+			silences = append(silences, *current)
+			current = nil
+		}
+	}
+
+	// silence that runs until the end of the file has no silence_end line
+	if current != nil {
+		silences = append(silences, *current)
+	}
+
+	return silences, scanner.Err()
+}
+
+func cutAudio(ctx context.Context, path string, start, end time.Duration) error {
+	tmpPath := path + ".trim.mp3"
+
+	out, err := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner", "-y",
+		"-i", path,
+		"-ss", fmt.Sprintf("%.3f", start.Seconds()),
+		"-t", fmt.Sprintf("%.3f", (end-start).Seconds()),
+		"-vn", "-codec:a", "libmp3lame", "-q:a", "2",
+		tmpPath,
+	).CombinedOutput()
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("ffmpeg trim %s: %w: %s", path, err, out)
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -3,12 +3,25 @@ package app
 import "github.com/NikhilSharmaWe/playree/playlist_creator/proto"
 
 type CreatePlaylistRequest struct {
+	JobID             string         `json:"job_id"`
 	PlayreePlaylistID string         `json:"playree_playlist_id"`
 	Tracks            []*proto.Track `json:"tracks"`
 }
 
+// ID identifies the job. Requests from older playree versions have no job id
+// and create a whole playlist, so the playlist id is unique enough.
+func (req CreatePlaylistRequest) ID() string {
+	if req.JobID != "" {
+		return req.JobID
+	}
+
+	return req.PlayreePlaylistID
+}
+
 type RabbitMQCreatePlaylistResponse struct {
-	PlayreePlaylistID string `json:"playree_playlist_id"`
-	Success           bool   `json:"success"`
-	Error             string `json:"error"`
+	JobID             string                `json:"job_id"`
+	PlayreePlaylistID string                `json:"playree_playlist_id"`
+	Tracks            []*proto.CreatedTrack `json:"tracks"`
+	Success           bool                  `json:"success"`
+	Error             string                `json:"error"`
 }
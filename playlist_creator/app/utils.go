@@ -2,18 +2,21 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/NikhilSharmaWe/playree/blobstore"
 	"github.com/NikhilSharmaWe/playree/playlist_creator/proto"
 	"github.com/NikhilSharmaWe/rabbitmq"
 	ytdl "github.com/NikhilSharmaWe/youtube/downloader"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
@@ -22,11 +25,25 @@ import (
 type Application struct {
 	Addr                 string
 	YTService            *youtube.Service
-	MinioClient          *minio.Client
-	MinioBucketName      string
+	BlobStore            blobstore.BlobStore
 	ConsumingClient      *rabbitmq.RabbitClient
 	PublishingConn       *amqp.Connection
 	CreatePlaylistClient proto.CreatePlaylistServiceClient
+	JobStore             JobStore
+
+	JobTimeout        time.Duration
+	LocalPlaylistsDir string
+	SpoolToDisk       bool
+	TranscodeAudio    bool
+	UploadPartSize    uint64
+
+	HLSEnabled  bool
+	HLSBitrates []string
+
+	TrimAudio             bool
+	TrimSilenceThreshold  string
+	TrimMinSilence        time.Duration
+	TrimDurationTolerance time.Duration
 }
 
 func NewApplication() (*Application, error) {
@@ -37,14 +54,7 @@ func NewApplication() (*Application, error) {
 		return nil, err
 	}
 
-	minioServerAddr := os.Getenv("MINIO_SERVER_ADDR")
-	minioAccessKey := os.Getenv("MINIO_ACCESS_KEY")
-	minioSecretKey := os.Getenv("MINIO_SECRET_KEY")
-	minioBucketName := os.Getenv("MINIO_BUCKET_NAME")
-
-	client, err := minio.New(minioServerAddr, &minio.Options{
-		Creds: credentials.NewStaticV4(minioAccessKey, minioSecretKey, ""),
-	})
+	blobStore, err := blobstore.NewFromEnv()
 	if err != nil {
 		return nil, err
 	}
@@ -81,58 +91,196 @@ func NewApplication() (*Application, error) {
 		return nil, err
 	}
 
+	jobTimeout, err := envDuration("JOB_TIMEOUT", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	spoolToDisk, err := envBool("SPOOL_TO_DISK", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// tracks are stored as <track id>.mp3 and served as audio/mpeg, so the
+	// downloaded WebM or M4A audio is re-encoded unless this is turned off
+	transcodeAudio, err := envBool("TRANSCODE_AUDIO", true)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadPartSize, err := envUint("UPLOAD_PART_SIZE", 16*1024*1024)
+	if err != nil {
+		return nil, err
+	}
+
+	hlsEnabled, err := envBool("HLS_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	trimAudio, err := envBool("TRIM_AUDIO", false)
+	if err != nil {
+		return nil, err
+	}
+
+	trimMinSilence, err := envDuration("TRIM_MIN_SILENCE", 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
+	trimDurationTolerance, err := envDuration("TRIM_DURATION_TOLERANCE", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Application{
 		Addr:                 addr,
 		YTService:            ytService,
-		MinioClient:          client,
-		MinioBucketName:      minioBucketName,
+		BlobStore:            blobStore,
 		ConsumingClient:      consumingClient,
 		PublishingConn:       publishingConn,
 		CreatePlaylistClient: createPlaylistClient,
+		JobStore:             NewJobStore(blobStore, "jobs"),
+
+		JobTimeout:        jobTimeout,
+		LocalPlaylistsDir: envString("LOCAL_PLAYLISTS_DIR", "./local-playlists"),
+		SpoolToDisk:       spoolToDisk,
+		TranscodeAudio:    transcodeAudio,
+		UploadPartSize:    uploadPartSize,
+
+		HLSEnabled:  hlsEnabled,
+		HLSBitrates: strings.Split(envString("HLS_BITRATES", "64k,128k,192k"), ","),
+
+		TrimAudio:             trimAudio,
+		TrimSilenceThreshold:  envString("TRIM_SILENCE_THRESHOLD", "-50dB"),
+		TrimMinSilence:        trimMinSilence,
+		TrimDurationTolerance: trimDurationTolerance,
 	}, nil
 }
 
-func (app *Application) getYTVideoIDs(tracks []*proto.Track) ([]string, error) {
-	videoIDs := []string{}
+// resolveTrack looks up the most relevant video for a track. It returns an
+// empty video ID when nothing matches.
+func (app *Application) resolveTrack(track *proto.Track) (string, error) {
+	query := fmt.Sprintf("%s : %s", track.Name, track.Artists)
+
+	call := app.YTService.Search.List([]string{"id"}).
+		Q(query).
+		Type("video").
+		MaxResults(1).
+		Order("relevance")
+	response, err := call.Do()
+	if err != nil {
+		return "", err
+	}
+
+	if len(response.Items) == 0 {
+		log.Printf("NO VIDEO FOUND: %s", query)
+		return "", nil
+	}
+
+	return response.Items[0].Id.VideoId, nil
+}
+
+// spoolToDisk reports whether tracks have to be downloaded to local files
+// before they are uploaded. Trimming and HLS packaging need the whole file to
+// work on.
+func (app *Application) spoolToDisk() bool {
+	return app.SpoolToDisk || app.TrimAudio || app.HLSEnabled
+}
+
+// jobDir is where a job spools its downloads. Every job has its own, so
+// concurrent jobs adding to the same playlist do not clean up each other.
+func (app *Application) jobDir(jobID string) string {
+	return filepath.Join(app.LocalPlaylistsDir, jobID)
+}
+
+func (app *Application) trackPath(jobID, trackKey string) string {
+	return filepath.Join(app.jobDir(jobID), path.Base(trackKey))
+}
+
+// downloadTrack downloads the audio of a video to outputPath and returns the
+// object metadata to store with it.
+func (app *Application) downloadTrack(ctx context.Context, outputPath, videoID string, expected time.Duration) (map[string]string, error) {
+	downloader := ytdl.GetDownloader(filepath.Dir(outputPath))
+
+	video, err := downloader.GetVideoContext(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, track := range tracks {
-		query := fmt.Sprintf("%s : %s", track.Name, track.Artists[1:len(track.Artists)-1])
+	if err := downloader.DownloadAudio(ctx, outputPath, video, "", ""); err != nil {
+		return nil, err
+	}
+
+	var metadata map[string]string
 
-		call := app.YTService.Search.List([]string{"id"}).
-			Q(query).
-			MaxResults(1).
-			Order("relevance")
-		response, err := call.Do()
+	if app.TrimAudio {
+		result, err := app.trimAudio(ctx, outputPath, expected)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, item := range response.Items {
-			switch item.Id.Kind {
-			case "youtube#video":
-				videoIDs = append(videoIDs, item.Id.VideoId)
-			default:
-				return nil, err
-			}
+		metadata = result.Metadata()
+
+		// trimming already re-encoded the file to MP3
+		if result.Trimmed() {
+			log.Printf("TRIMMED: %s: original %s, kept %s - %s", outputPath, result.OriginalDuration, result.Start, result.End)
+			return metadata, nil
+		}
+	}
+
+	if app.TranscodeAudio {
+		if err := transcodeFile(ctx, outputPath); err != nil {
+			return nil, err
 		}
 	}
 
-	return videoIDs, nil
+	return metadata, nil
 }
 
-func (app *Application) downloadToAudioLocally(req CreatePlaylistRequest, videoIDs []string) error {
-	outputDir := fmt.Sprintf("./local-playlists/%s", req.PlayreePlaylistID)
+func (app *Application) uploadFile(ctx context.Context, key, filePath string, opts blobstore.PutOptions) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
 
-	downloader := ytdl.GetDownloader(outputDir)
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	opts.PartSize = app.UploadPartSize
+
+	return app.BlobStore.Put(ctx, key, file, info.Size(), opts)
+}
+
+// CleanupLocalPlaylists removes scratch directories left behind by jobs that
+// have no checkpoint anymore. Directories of unfinished jobs are kept so the
+// downloaded tracks are reused when the job is redelivered.
+func (app *Application) CleanupLocalPlaylists(ctx context.Context) error {
+	entries, err := os.ReadDir(app.LocalPlaylistsDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
 
-	for i, videoID := range videoIDs {
-		filename := fmt.Sprintf("%s_%s_%s.mp3", strconv.Itoa(i), req.Tracks[i].Name, req.Tracks[i].Artists)
-		video, _, err := downloader.GetVideoWithFormat(videoID, outputDir)
+	for _, entry := range entries {
+		cp, err := app.JobStore.Get(ctx, entry.Name())
 		if err != nil {
 			return err
 		}
 
-		if err := downloader.DownloadAudio(context.Background(), fmt.Sprintf("%s/%s", outputDir, filename), video, "", ""); err != nil {
+		if cp != nil {
+			log.Printf("KEEPING LOCAL PLAYLIST OF UNFINISHED JOB: %s", entry.Name())
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(app.LocalPlaylistsDir, entry.Name())); err != nil {
 			return err
 		}
 	}
@@ -140,29 +288,57 @@ func (app *Application) downloadToAudioLocally(req CreatePlaylistRequest, videoI
 	return nil
 }
 
-func (app *Application) pushToMinio(playreePlaylistID string) error {
-	folderPath := fmt.Sprintf("./local-playlists/%s", playreePlaylistID)
+func fileExists(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return err == nil
+}
+
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return def
+}
+
+func envBool(key string, def bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
 
-	cwd, err := os.Getwd()
+	b, err := strconv.ParseBool(v)
 	if err != nil {
-		return err
+		return false, fmt.Errorf("invalid %s: %w", key, err)
 	}
 
-	return filepath.WalkDir(folderPath, func(path string, d fs.DirEntry, err error) error {
-		fileInfo, err := os.Stat(path)
-		if err != nil {
-			return err
-		}
+	return b, nil
+}
 
-		if fileInfo.IsDir() {
-			return nil
-		}
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
 
-		absolutefilePath := cwd + "/" + strings.TrimPrefix(path, "./")
-		key := strings.TrimPrefix(path, "local-playlists/")
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
 
-		_, err = app.MinioClient.FPutObject(context.Background(), app.MinioBucketName, key, absolutefilePath, minio.PutObjectOptions{})
+	return d, nil
+}
 
-		return err
-	})
+func envUint(key string, def uint64) (uint64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return n, nil
 }
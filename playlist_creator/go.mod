@@ -3,10 +3,11 @@ module github.com/NikhilSharmaWe/playree/playlist_creator
 go 1.22.2
 
 require (
+	github.com/NikhilSharmaWe/playree/blobstore v0.0.0-00010101000000-000000000000
 	github.com/NikhilSharmaWe/rabbitmq v0.0.0-20240429163106-fcf8f783faab
 	github.com/NikhilSharmaWe/youtube v0.0.0-20240428052408-1661e944b0a6
+	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
-	github.com/minio/minio-go/v7 v7.0.70
 	github.com/rabbitmq/amqp091-go v1.9.0
 	golang.org/x/sync v0.7.0
 	google.golang.org/api v0.176.1
@@ -33,13 +34,13 @@ require (
 	github.com/golang/protobuf v1.5.4 // indirect
 	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
 	github.com/google/s2a-go v0.1.7 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/googleapis/enterprise-certificate-proxy v0.3.2 // indirect
 	github.com/googleapis/gax-go/v2 v2.12.3 // indirect
 	github.com/klauspost/compress v1.17.6 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.6 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
 	github.com/minio/md5-simd v1.1.2 // indirect
+	github.com/minio/minio-go/v7 v7.0.70 // indirect
 	github.com/rs/xid v1.5.0 // indirect
 	github.com/vbauerster/mpb/v5 v5.4.0 // indirect
 	go.opencensus.io v0.24.0 // indirect
@@ -55,3 +56,5 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240415180920-8c6c420018be // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 )
+
+replace github.com/NikhilSharmaWe/playree/blobstore => ../blobstore
@@ -23,6 +23,10 @@ func main() {
 
 	defer application.ConsumingClient.Close()
 
+	if err := application.CleanupLocalPlaylists(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
 	createPlaylistRequestMSGBus, err := setupRabbitMQForStartup(application)
 	if err != nil {
 		log.Fatal(err)
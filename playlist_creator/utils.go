@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/NikhilSharmaWe/playree/playlist_creator/app"
 	"github.com/NikhilSharmaWe/playree/playlist_creator/proto"
@@ -38,13 +37,17 @@ func handleCreatePlaylistRequests(application *app.Application, msg amqp.Deliver
 		return err
 	}
 
+	defer publishingClient.Close()
+
 	req := proto.CreatePlaylistRequest{}
 
 	if err := json.Unmarshal(msg.Body, &req); err != nil {
+		// a malformed request will never succeed, drop it instead of redelivering
+		msg.Nack(false, false)
 		return err
 	}
 
-	ctx, cancelFunc := context.WithTimeout(context.Background(), 1*time.Minute)
+	ctx, cancelFunc := context.WithTimeout(context.Background(), application.JobTimeout)
 	defer cancelFunc()
 
 	var response *app.RabbitMQCreatePlaylistResponse
@@ -53,13 +56,16 @@ func handleCreatePlaylistRequests(application *app.Application, msg amqp.Deliver
 	if err != nil {
 		log.Println("ERROR: CREATE PLAYLIST: ", err)
 		response = &app.RabbitMQCreatePlaylistResponse{
+			JobID:             req.JobId,
 			PlayreePlaylistID: req.PlayreePlaylistId,
 			Success:           false,
 			Error:             fmt.Sprint("CREATE PLAYLIST SERVICE: ", err.Error()),
 		}
 	} else {
 		response = &app.RabbitMQCreatePlaylistResponse{
+			JobID:             resp.JobId,
 			PlayreePlaylistID: resp.PlayreePlaylistId,
+			Tracks:            resp.Tracks,
 			Success:           true,
 		}
 	}
@@ -69,9 +75,15 @@ func handleCreatePlaylistRequests(application *app.Application, msg amqp.Deliver
 		return err
 	}
 
-	return publishingClient.Send(context.Background(), "create-playlist", msg.ReplyTo, amqp.Publishing{
+	if err := publishingClient.Send(context.Background(), "create-playlist", msg.ReplyTo, amqp.Publishing{
 		ContentType:  "application/json",
 		Body:         body,
 		DeliveryMode: amqp.Persistent,
-	})
+	}); err != nil {
+		return err
+	}
+
+	// the request is only acknowledged once it is answered, if the service dies
+	// before that RabbitMQ redelivers it and the job resumes from its checkpoint
+	return msg.Ack(false)
 }
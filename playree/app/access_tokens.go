@@ -0,0 +1,155 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Scopes of personal access tokens. Read-only tokens may only send GET and
+// HEAD requests.
+const (
+	ScopeRead      = "read"
+	ScopeReadWrite = "read-write"
+)
+
+// accessTokenPrefix marks personal access tokens, so they are easy to spot in
+// scripts and logs.
+const accessTokenPrefix = "pat_"
+
+type accessTokensPage struct {
+	BaseURL  string
+	Tokens   []models.AccessTokenDBModel
+	NewToken string
+}
+
+func (app *Application) HandleAccessTokens(c echo.Context) error {
+	return app.renderAccessTokens(c, "")
+}
+
+// HandleCreateAccessToken creates a personal access token. Only its hash is
+// stored, the token is shown once, right after it is created.
+func (app *Application) HandleCreateAccessToken(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	name := c.FormValue("name")
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	scope := c.FormValue("scope")
+	if scope != ScopeRead && scope != ScopeReadWrite {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidScope)
+	}
+
+	token, err := newPlaylistToken()
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	token = accessTokenPrefix + token
+
+	if err := app.AccessTokenStore.Create(models.AccessTokenDBModel{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashAccessToken(token),
+		Scope:     scope,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return app.renderAccessTokens(c, token)
+}
+
+// HandleRevokeAccessToken revokes a token. Revoked tokens stay listed, so it
+// is clear which clients lost access.
+func (app *Application) HandleRevokeAccessToken(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := app.AccessTokenStore.Update(
+		map[string]any{"revoked_at": time.Now()},
+		"id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), userID,
+	); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/access-tokens")
+}
+
+func (app *Application) renderAccessTokens(c echo.Context, newToken string) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	tokens, err := app.AccessTokenStore.GetManyOrdered("created_at DESC", "user_id = ?", userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := c.Render(http.StatusOK, "access_tokens.html", accessTokensPage{
+		BaseURL:  baseURL(c),
+		Tokens:   tokens,
+		NewToken: newToken,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// authenticateAccessToken returns the unrevoked token with the hash of token.
+func (app *Application) authenticateAccessToken(token string) (*models.AccessTokenDBModel, error) {
+	accessToken, err := app.AccessTokenStore.GetOne("token_hash = ? AND revoked_at IS NULL", hashAccessToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInvalidAccessToken
+		}
+
+		return nil, err
+	}
+
+	if err := app.AccessTokenStore.Update(map[string]any{"last_used_at": time.Now()}, "id = ?", accessToken.ID); err != nil {
+		return nil, err
+	}
+
+	return accessToken, nil
+}
+
+// hashAccessToken is the hash stored for token. Tokens are random, so a
+// plain sha256 is enough to keep them out of the database.
+func hashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// scopeAllows reports whether a token of scope may send a request with method.
+func scopeAllows(scope, method string) bool {
+	if scope == ScopeReadWrite {
+		return true
+	}
+
+	return method == http.MethodGet || method == http.MethodHead
+}
@@ -0,0 +1,337 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+	"github.com/zmb3/spotify/v2"
+)
+
+// maxAddTracks caps the tracks of one add job, which is also the number of
+// ids the Spotify API resolves in one request. ErrTooManyTracksToAdd names it.
+const maxAddTracks = 50
+
+type addTracksPage struct {
+	Playlist   *models.PlaylistsDBModel
+	Query      string
+	Results    []*models.Track
+	Playlists  []models.PlaylistsDBModel
+	From       string
+	FromTracks []models.TrackDBModel
+	Jobs       []models.JobDBModel
+}
+
+// HandleAddTracks shows the ways an editor can add tracks: Spotify search,
+// a pasted link and the tracks of the playlists they can listen to. It also
+// lists the recent add and sync jobs of the playlist.
+func (app *Application) HandleAddTracks(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	page := addTracksPage{
+		Playlist: playlist,
+		Query:    strings.TrimSpace(c.QueryParam("q")),
+		From:     c.QueryParam("from"),
+	}
+
+	if page.Query != "" {
+		client, err := app.spotifyClient(c.Request().Context(), userID)
+		if err != nil {
+			c.Logger().Error(err)
+			return err
+		}
+
+		result, err := client.Search(c.Request().Context(), page.Query, spotify.SearchTypeTrack, spotify.Limit(20))
+		if err != nil {
+			c.Logger().Error(err)
+			return err
+		}
+
+		if result.Tracks != nil {
+			for _, track := range result.Tracks.Tracks {
+				page.Results = append(page.Results, spotifyTrackToModel(track.SimpleTrack, track.Album.Name))
+			}
+		}
+	}
+
+	page.Playlists, err = app.PlaylistStore.GetMany(
+		[]string{"playlist_id", "playlist_name"},
+		"playlist_id <> ? AND (user_id = ? OR playlist_id IN (SELECT playlist_id FROM playlist_shares WHERE user_id = ?))",
+		playlist.PlaylistID, userID, userID,
+	)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if page.From != "" {
+		if _, _, err := app.authorizePlaylist(c, page.From, RoleViewer); err != nil {
+			return err
+		}
+
+		page.FromTracks, err = app.TrackStore.GetManyOrdered(
+			[]string{"track_id", "title", "artists", "album"},
+			"position",
+			"playlist_id = ?", page.From,
+		)
+		if err != nil {
+			c.Logger().Error(err)
+			return err
+		}
+	}
+
+	page.Jobs, err = app.JobStore.GetPage(
+		[]string{"job_id", "user_id", "kind", "status", "error", "track_count", "created_at"},
+		"created_at DESC", 10, 0,
+		"playlist_id = ? AND kind IN ?", playlist.PlaylistID, []string{jobAdd, jobSync},
+	)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := c.Render(http.StatusOK, "add_tracks.html", page); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// HandleCreateAddJob starts an add job for the tracks picked on the add page:
+// spotify_track_id values from the search, a Spotify link, or track_id values
+// of tracks in playlists the editor can listen to. The tracks count against
+// the quota of the playlist owner and are attributed to the editor.
+func (app *Application) HandleCreateAddJob(c echo.Context) error {
+	playlist := getPlaylist(c)
+	ctx := c.Request().Context()
+
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	tracks, err := app.resolveTracks(c, userID, form["spotify_track_id"], strings.TrimSpace(form.Get("link")), form["track_id"])
+	if err != nil {
+		return app.httpError(c, err)
+	}
+
+	if _, err := app.startAddJob(ctx, playlist, userID, tracks); err != nil {
+		return app.httpError(c, err)
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/playlist/"+playlist.PlaylistID+"/add")
+}
+
+// resolveTracks looks up the tracks to add: Spotify track ids, a Spotify
+// link, or ids of tracks in playlists the visitor can listen to, whichever
+// is given first.
+func (app *Application) resolveTracks(c echo.Context, userID string, spotifyTrackIDs []string, link string, trackIDs []string) ([]*models.Track, error) {
+	ctx := c.Request().Context()
+
+	switch {
+	case len(spotifyTrackIDs) > 0:
+		client, err := app.spotifyClient(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		return getSpotifyTracks(ctx, client, spotifyTrackIDs)
+
+	case link != "":
+		client, err := app.spotifyClient(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		return getTracksFromSpotifyLink(ctx, client, link)
+
+	case len(trackIDs) > 0:
+		return app.getTracksFromPlaylists(c, trackIDs)
+	}
+
+	return nil, models.ErrNoTracksToAdd
+}
+
+// getTracksFromPlaylists copies the metadata of existing tracks, in the order
+// of trackIDs. Every track has to be in a playlist the visitor can listen to.
+func (app *Application) getTracksFromPlaylists(c echo.Context, trackIDs []string) ([]*models.Track, error) {
+	rows, err := app.TrackStore.GetMany(
+		[]string{"track_id", "playlist_id", "title", "artists", "album", "duration_ms", "spotify_track_id"},
+		"track_id IN ?", trackIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]models.TrackDBModel)
+	authorized := make(map[string]bool)
+
+	for _, row := range rows {
+		if !authorized[row.PlaylistID] {
+			if _, _, err := app.authorizePlaylist(c, row.PlaylistID, RoleViewer); err != nil {
+				return nil, err
+			}
+
+			authorized[row.PlaylistID] = true
+		}
+
+		byID[row.TrackID] = row
+	}
+
+	tracks := []*models.Track{}
+	for _, trackID := range trackIDs {
+		row, ok := byID[trackID]
+		if !ok {
+			return nil, models.ErrTrackNotExists
+		}
+
+		tracks = append(tracks, &models.Track{
+			Name:           row.Title,
+			Artists:        row.Artists,
+			Album:          row.Album,
+			DurationMs:     row.DurationMs,
+			SpotifyTrackID: row.SpotifyTrackID,
+		})
+	}
+
+	return tracks, nil
+}
+
+// spotifyClient returns a Spotify client for userID, refreshing and storing
+// the token first if it expired.
+func (app *Application) spotifyClient(ctx context.Context, userID string) (*spotify.Client, error) {
+	token, err := app.TokenStore.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == nil {
+		return nil, models.ErrTokenNotExists
+	}
+
+	checkedToken, err := app.Authenticator.RefreshToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkedToken.AccessToken != token.AccessToken {
+		if err := app.TokenStore.Update(ctx, userID, checkedToken); err != nil {
+			return nil, err
+		}
+	}
+
+	return spotify.New(app.Authenticator.Client(ctx, checkedToken)), nil
+}
+
+func getSpotifyTracks(ctx context.Context, client *spotify.Client, trackIDs []string) ([]*models.Track, error) {
+	if len(trackIDs) > maxAddTracks {
+		return nil, models.ErrTooManyTracksToAdd
+	}
+
+	ids := []spotify.ID{}
+	for _, trackID := range trackIDs {
+		ids = append(ids, spotify.ID(trackID))
+	}
+
+	fullTracks, err := client.GetTracks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := []*models.Track{}
+	for _, track := range fullTracks {
+		// unknown ids come back as null
+		if track == nil {
+			continue
+		}
+
+		tracks = append(tracks, spotifyTrackToModel(track.SimpleTrack, track.Album.Name))
+	}
+
+	return tracks, nil
+}
+
+// getTracksFromSpotifyLink resolves a link or URI of a Spotify track, album
+// or playlist, e.g. https://open.spotify.com/album/<id> or spotify:track:<id>.
+func getTracksFromSpotifyLink(ctx context.Context, client *spotify.Client, link string) ([]*models.Track, error) {
+	kind, id, err := parseSpotifyLink(link)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "track":
+		track, err := client.GetTrack(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*models.Track{spotifyTrackToModel(track.SimpleTrack, track.Album.Name)}, nil
+
+	case "album":
+		album, err := client.GetAlbum(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		tracks := []*models.Track{}
+		for _, track := range album.Tracks.Tracks {
+			tracks = append(tracks, spotifyTrackToModel(track, album.Name))
+		}
+
+		return tracks, nil
+
+	case "playlist":
+		tracks, _, _, err := getNameAndTracksFromPlaylist(client, id.String())
+		return tracks, err
+	}
+
+	return nil, models.ErrInvalidSpotifyLink
+}
+
+func parseSpotifyLink(link string) (string, spotify.ID, error) {
+	var parts []string
+
+	if strings.HasPrefix(link, "spotify:") {
+		parts = strings.Split(strings.TrimPrefix(link, "spotify:"), ":")
+	} else {
+		u, err := url.Parse(link)
+		if err != nil {
+			return "", "", models.ErrInvalidSpotifyLink
+		}
+
+		parts = strings.Split(strings.Trim(u.Path, "/"), "/")
+
+		// localized links look like /intl-de/track/<id>
+		if len(parts) == 3 && strings.HasPrefix(parts[0], "intl-") {
+			parts = parts[1:]
+		}
+	}
+
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", models.ErrInvalidSpotifyLink
+	}
+
+	switch parts[0] {
+	case "track", "album", "playlist":
+		return parts[0], spotify.ID(parts[1]), nil
+	}
+
+	return "", "", models.ErrInvalidSpotifyLink
+}
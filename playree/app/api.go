@@ -0,0 +1,502 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// apiPage is one page of a list. NextOffset is null on the last page.
+type apiPage struct {
+	Items      any  `json:"items"`
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	NextOffset *int `json:"next_offset"`
+}
+
+type apiErrorBody struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+type apiUser struct {
+	models.UserDBModel
+	Usage *models.Usage `json:"usage"`
+}
+
+type apiPlaylist struct {
+	models.PlaylistsDBModel
+	Role       string `json:"role"`
+	TrackCount *int64 `json:"track_count,omitempty"`
+}
+
+type apiTrack struct {
+	models.TrackDBModel
+	Plays *models.TrackPlayCount `json:"plays,omitempty"`
+}
+
+type apiCreatePlaylistRequest struct {
+	SpotifyPlaylist string `json:"spotify_playlist"`
+}
+
+type apiUpdatePlaylistRequest struct {
+	Name string `json:"name"`
+}
+
+type apiAddTracksRequest struct {
+	SpotifyTrackIDs []string `json:"spotify_track_ids"`
+	Link            string   `json:"link"`
+	TrackIDs        []string `json:"track_ids"`
+}
+
+// apiTrackFields are the track columns the API returns.
+var apiTrackFields = []string{
+	"track_id", "playlist_id", "title", "artists", "album", "duration_ms", "position",
+	"video_id", "hls_key <> '' AS has_hls", "spotify_track_id", "size_bytes", "added_by", "inserted_at",
+}
+
+// APIRoutes registers the JSON API, documented in API.md. Requests are
+// authenticated with the session or a personal access token.
+func (app *Application) APIRoutes(g *echo.Group) {
+	g.Use(app.APIErrors, app.BearerAuth)
+
+	// share links open the player without an account
+	g.GET("/shares/:token", app.APIGetShare)
+	g.GET("/playlists/:playlist_id/tracks", app.APIListTracks, app.RequirePlaylistRole(RoleViewer))
+
+	g.GET("/me", app.APIGetMe, app.RequireAPIUser)
+
+	g.GET("/playlists", app.APIListPlaylists, app.RequireAPIUser)
+	g.POST("/playlists", app.APICreatePlaylist, app.RequireAPIUser)
+	g.GET("/playlists/:playlist_id", app.APIGetPlaylist, app.RequireAPIUser, app.RequirePlaylistRole(RoleViewer))
+	g.PATCH("/playlists/:playlist_id", app.APIUpdatePlaylist, app.RequireAPIUser, app.RequirePlaylistRole(RoleEditor))
+	g.DELETE("/playlists/:playlist_id", app.APIDeletePlaylist, app.RequireAPIUser, app.RequirePlaylistRole(RoleOwner))
+	g.POST("/playlists/:playlist_id/sync", app.APISyncPlaylist, app.RequireAPIUser, app.RequirePlaylistRole(RoleEditor))
+	g.POST("/playlists/:playlist_id/tracks", app.APIAddTracks, app.RequireAPIUser, app.RequirePlaylistRole(RoleEditor))
+	g.PUT("/playlists/:playlist_id/tracks/order", app.HandleReorderTracks, app.RequireAPIUser, app.RequirePlaylistRole(RoleEditor))
+	g.DELETE("/playlists/:playlist_id/tracks/:track_id", app.APIRemoveTrack, app.RequireAPIUser, app.RequirePlaylistRole(RoleEditor))
+	g.GET("/playlists/:playlist_id/plays", app.HandlePlaylistPlays, app.RequireAPIUser, app.RequirePlaylistRole(RoleViewer))
+	g.GET("/playlists/:playlist_id/jobs", app.APIListPlaylistJobs, app.RequireAPIUser, app.RequirePlaylistRole(RoleEditor))
+
+	g.GET("/tracks/:track_id", app.APIGetTrack, app.RequireAPIUser)
+
+	g.GET("/jobs", app.APIListJobs, app.RequireAPIUser)
+	g.GET("/jobs/:job_id", app.APIGetJob, app.RequireAPIUser)
+}
+
+// APIErrors writes the errors of API handlers as JSON bodies with the status
+// of the error, see errorStatus. Internal errors are logged and not shown.
+func (app *Application) APIErrors(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		if err == nil || c.Response().Committed {
+			return err
+		}
+
+		status, message := errorStatus(err), err.Error()
+
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			message = fmt.Sprint(httpErr.Message)
+		}
+
+		if status >= http.StatusInternalServerError {
+			c.Logger().Error(err)
+			message = http.StatusText(status)
+		}
+
+		return c.JSON(status, apiErrorBody{
+			Error: apiErrorDetail{
+				Status:  status,
+				Message: message,
+			},
+		})
+	}
+}
+
+// RequireAPIUser rejects API requests that do not act for a user.
+func (app *Application) RequireAPIUser(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, ok := app.currentUserID(c)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, models.ErrAuthenticationRequired)
+		}
+
+		c.Set("user_id", userID)
+
+		return next(c)
+	}
+}
+
+func (app *Application) APIGetMe(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	user, err := app.UserStore.GetOne("user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+
+	usage, err := app.getUsage(userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, apiUser{
+		UserDBModel: *user,
+		Usage:       usage,
+	})
+}
+
+// APIListPlaylists lists the playlists the user owns or that are shared with
+// them, by name.
+func (app *Application) APIListPlaylists(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		return err
+	}
+
+	items, err := app.userPlaylists(userID, limit+1, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newAPIPage(items, limit, offset))
+}
+
+// APICreatePlaylist starts importing a Spotify playlist, given by link, URI
+// or id. The playlist exists once the returned job is done.
+func (app *Application) APICreatePlaylist(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req apiCreatePlaylistRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	spotifyPlaylistID, err := spotifyPlaylistID(strings.TrimSpace(req.SpotifyPlaylist))
+	if err != nil {
+		return err
+	}
+
+	job, err := app.startCreateJob(c.Request().Context(), userID, spotifyPlaylistID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func (app *Application) APIGetPlaylist(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	count, err := app.TrackStore.Count("playlist_id = ?", playlist.PlaylistID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, apiPlaylist{
+		PlaylistsDBModel: *playlist,
+		Role:             getPlaylistRole(c),
+		TrackCount:       &count,
+	})
+}
+
+func (app *Application) APIUpdatePlaylist(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	var req apiUpdatePlaylistRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	if err := app.renamePlaylist(playlist, req.Name); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, apiPlaylist{
+		PlaylistsDBModel: *playlist,
+		Role:             getPlaylistRole(c),
+	})
+}
+
+func (app *Application) APIDeletePlaylist(c echo.Context) error {
+	if err := app.deletePlaylist(c, getPlaylist(c)); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (app *Application) APIListTracks(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		return err
+	}
+
+	tracks, err := app.TrackStore.GetPage(apiTrackFields, "position", limit+1, offset, "playlist_id = ?", playlist.PlaylistID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newAPIPage(tracks, limit, offset))
+}
+
+// APIAddTracks starts a job adding tracks, given as Spotify track ids, a
+// Spotify link or ids of tracks the user can listen to.
+func (app *Application) APIAddTracks(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req apiAddTracksRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	tracks, err := app.resolveTracks(c, userID, req.SpotifyTrackIDs, strings.TrimSpace(req.Link), req.TrackIDs)
+	if err != nil {
+		return err
+	}
+
+	job, err := app.startAddJob(c.Request().Context(), getPlaylist(c), userID, tracks)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func (app *Application) APIRemoveTrack(c echo.Context) error {
+	if err := app.removeTrack(c, getPlaylist(c), c.Param("track_id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (app *Application) APIListPlaylistJobs(c echo.Context) error {
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := app.JobStore.GetPage([]string{"*"}, "created_at DESC", limit+1, offset, "playlist_id = ?", getPlaylist(c).PlaylistID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newAPIPage(jobs, limit, offset))
+}
+
+func (app *Application) APIGetTrack(c echo.Context) error {
+	track, _, err := app.authorizeTrack(c, RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	counts, err := app.PlayStore.TrackCounts(track.PlaylistID)
+	if err != nil {
+		return err
+	}
+
+	track.HasHLS = track.HLSKey != ""
+
+	resp := apiTrack{
+		TrackDBModel: *track,
+		Plays:        &models.TrackPlayCount{TrackID: track.TrackID},
+	}
+
+	for i := range counts {
+		if counts[i].TrackID == track.TrackID {
+			resp.Plays = &counts[i]
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// APIListJobs lists the jobs the user started.
+func (app *Application) APIListJobs(c echo.Context) error {
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := app.JobStore.GetPage([]string{"*"}, "created_at DESC", limit+1, offset, "user_id = ?", c.Get("user_id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newAPIPage(jobs, limit, offset))
+}
+
+func (app *Application) APIGetJob(c echo.Context) error {
+	job, err := app.userJob(c.Get("user_id").(string), c.Param("job_id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// APIGetShare returns the playlist of an anonymous share link, so the player
+// can load its tracks.
+func (app *Application) APIGetShare(c echo.Context) error {
+	token, err := app.getPlaylistToken(c, playlistTokenShare)
+	if err != nil {
+		return err
+	}
+
+	playlist, err := app.PlaylistStore.GetOne("playlist_id = ?", token.PlaylistID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, apiPlaylist{
+		PlaylistsDBModel: *playlist,
+		Role:             RoleViewer,
+	})
+}
+
+// userPlaylists lists the playlists userID owns or that are shared with them,
+// by name, with the role of the user.
+func (app *Application) userPlaylists(userID string, limit, offset int) ([]apiPlaylist, error) {
+	playlists, err := app.PlaylistStore.GetPage(
+		[]string{"user_id", "playlist_id", "playlist_name", "artwork_url", "spotify_playlist_id"},
+		"playlist_name, playlist_id", limit, offset,
+		"user_id = ? OR playlist_id IN (SELECT playlist_id FROM playlist_shares WHERE user_id = ?)", userID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	shares, err := app.PlaylistShareStore.GetManyOrdered("created_at", "user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]string)
+	for _, share := range shares {
+		roles[share.PlaylistID] = share.Role
+	}
+
+	items := []apiPlaylist{}
+	for _, playlist := range playlists {
+		role := roles[playlist.PlaylistID]
+		if playlist.UserID == userID {
+			role = RoleOwner
+		}
+
+		items = append(items, apiPlaylist{PlaylistsDBModel: playlist, Role: role})
+	}
+
+	return items, nil
+}
+
+// userJob loads a job userID may see: one they started, or an add or sync
+// job of a playlist they can edit. Jobs name who started them and why they
+// failed, so viewers do not see them.
+func (app *Application) userJob(userID, jobID string) (*models.JobDBModel, error) {
+	job, err := app.JobStore.GetOne("job_id = ?", jobID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrJobNotExists
+		}
+
+		return nil, err
+	}
+
+	if job.UserID == userID {
+		return job, nil
+	}
+
+	if job.Kind != jobCreate {
+		if _, _, err := app.authorizeUserPlaylist(userID, job.PlaylistID, RoleEditor); err == nil {
+			return job, nil
+		}
+	}
+
+	return nil, models.ErrJobNotExists
+}
+
+// spotifyPlaylistID accepts a Spotify playlist link, URI or bare id.
+func spotifyPlaylistID(value string) (string, error) {
+	if value == "" {
+		return "", models.ErrInvalidSpotifyLink
+	}
+
+	if !strings.ContainsAny(value, "/:") {
+		return value, nil
+	}
+
+	kind, id, err := parseSpotifyLink(value)
+	if err != nil || kind != "playlist" {
+		return "", models.ErrInvalidSpotifyLink
+	}
+
+	return id.String(), nil
+}
+
+// pageParams reads the limit and offset query parameters.
+func pageParams(c echo.Context) (int, int, error) {
+	limit, offset := defaultPageSize, 0
+
+	if value := c.QueryParam("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxPageSize {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s: limit has to be between 1 and %d", models.ErrInvalidRequest, maxPageSize))
+		}
+
+		limit = n
+	}
+
+	if value := c.QueryParam("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s: offset has to be 0 or more", models.ErrInvalidRequest))
+		}
+
+		offset = n
+	}
+
+	return limit, offset, nil
+}
+
+// newAPIPage builds a page from items fetched with limit+1, the extra item
+// tells whether there is a next page.
+func newAPIPage[T any](items []T, limit, offset int) apiPage {
+	page := apiPage{
+		Items:  items,
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	if items == nil {
+		page.Items = []T{}
+	}
+
+	if len(items) > limit {
+		page.Items = items[:limit]
+		next := offset + limit
+		page.NextOffset = &next
+	}
+
+	return page
+}
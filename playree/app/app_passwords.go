@@ -0,0 +1,100 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type appPasswordsPage struct {
+	UserID      string
+	BaseURL     string
+	Passwords   []models.AppPasswordDBModel
+	NewPassword string
+}
+
+func (app *Application) HandleAppPasswords(c echo.Context) error {
+	return app.renderAppPasswords(c, "")
+}
+
+// HandleCreateAppPassword creates an app password for Subsonic clients. The
+// password is shown once, right after it is created.
+func (app *Application) HandleCreateAppPassword(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	name := c.FormValue("name")
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	password, err := newPlaylistToken()
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	secret, err := encryptSecret(app.AppPasswordKey, password)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := app.AppPasswordStore.Create(models.AppPasswordDBModel{
+		ID:     uuid.NewString(),
+		UserID: userID,
+		Name:   name,
+		Secret: secret,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return app.renderAppPasswords(c, password)
+}
+
+func (app *Application) HandleDeleteAppPassword(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := app.AppPasswordStore.Delete("id = ? AND user_id = ?", c.Param("id"), userID); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/app-passwords")
+}
+
+func (app *Application) renderAppPasswords(c echo.Context, newPassword string) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	passwords, err := app.AppPasswordStore.GetManyOrdered("created_at DESC", "user_id = ?", userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := c.Render(http.StatusOK, "app_passwords.html", appPasswordsPage{
+		UserID:      userID,
+		BaseURL:     baseURL(c),
+		Passwords:   passwords,
+		NewPassword: newPassword,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Playlist roles, each one includes the rights of the ones before it.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+func roleAtLeast(role, minRole string) bool {
+	return roleRanks[role] >= roleRanks[minRole]
+}
+
+// RequirePlaylistRole loads the playlist named by the playlist_id route
+// parameter and rejects the request unless the visitor has at least minRole
+// on it. Handlers get the playlist with getPlaylist.
+func (app *Application) RequirePlaylistRole(minRole string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			playlist, role, err := app.authorizePlaylist(c, c.Param("playlist_id"), minRole)
+			if err != nil {
+				return err
+			}
+
+			c.Set("playlist", playlist)
+			c.Set("playlist_role", role)
+
+			return next(c)
+		}
+	}
+}
+
+// getPlaylist returns the playlist loaded by RequirePlaylistRole.
+func getPlaylist(c echo.Context) *models.PlaylistsDBModel {
+	return c.Get("playlist").(*models.PlaylistsDBModel)
+}
+
+func getPlaylistRole(c echo.Context) string {
+	return c.Get("playlist_role").(string)
+}
+
+func (app *Application) authorizePlaylist(c echo.Context, playlistID, minRole string) (*models.PlaylistsDBModel, string, error) {
+	playlist, err := app.PlaylistStore.GetOne("playlist_id = ?", playlistID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", echo.NewHTTPError(http.StatusNotFound, models.ErrPlaylistNotExists)
+		}
+
+		c.Logger().Error(err)
+		return nil, "", err
+	}
+
+	role, err := app.playlistRole(c, playlist)
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, "", err
+	}
+
+	if !roleAtLeast(role, minRole) {
+		return nil, "", echo.NewHTTPError(http.StatusForbidden, models.ErrPlaylistAccessDenied)
+	}
+
+	return playlist, role, nil
+}
+
+// playlistRole is the role of the visitor on playlist: owner, a role granted
+// by a share, viewer for anonymous visitors who opened a share link, or ""
+// for no access.
+func (app *Application) playlistRole(c echo.Context, playlist *models.PlaylistsDBModel) (string, error) {
+	if userID, ok := app.currentUserID(c); ok {
+		role, err := app.userPlaylistRole(userID, playlist)
+		if err != nil || role != "" {
+			return role, err
+		}
+	}
+
+	if token, err := getContext(c, "share_token"); err == nil {
+		_, err := app.PlaylistTokenStore.GetOne(
+			"token = ? AND playlist_id = ? AND kind = ? AND revoked_at IS NULL", token, playlist.PlaylistID, playlistTokenShare,
+		)
+		if err == nil {
+			return RoleViewer, nil
+		}
+
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+	}
+
+	return "", nil
+}
+
+// userPlaylistRole is the role of userID on playlist: owner, a role granted
+// by a share, or "" for no access.
+func (app *Application) userPlaylistRole(userID string, playlist *models.PlaylistsDBModel) (string, error) {
+	if playlist.UserID == userID {
+		return RoleOwner, nil
+	}
+
+	share, err := app.PlaylistShareStore.GetOne("playlist_id = ? AND user_id = ?", playlist.PlaylistID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return share.Role, nil
+}
+
+// authorizeUserPlaylist is authorizePlaylist for callers without a request
+// context, such as the gRPC service. Its errors are the ones of
+// models/errors.go.
+func (app *Application) authorizeUserPlaylist(userID, playlistID, minRole string) (*models.PlaylistsDBModel, string, error) {
+	playlist, err := app.PlaylistStore.GetOne("playlist_id = ?", playlistID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", models.ErrPlaylistNotExists
+		}
+
+		return nil, "", err
+	}
+
+	role, err := app.userPlaylistRole(userID, playlist)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !roleAtLeast(role, minRole) {
+		return nil, "", models.ErrPlaylistAccessDenied
+	}
+
+	return playlist, role, nil
+}
+
+// authorizeTrack loads the track named by the track_id route parameter and
+// makes sure the visitor has at least minRole on its playlist.
+func (app *Application) authorizeTrack(c echo.Context, minRole string) (*models.TrackDBModel, *models.PlaylistsDBModel, error) {
+	track, err := app.TrackStore.GetOne("track_id = ?", c.Param("track_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, echo.NewHTTPError(http.StatusNotFound, models.ErrTrackNotExists)
+		}
+
+		c.Logger().Error(err)
+		return nil, nil, err
+	}
+
+	playlist, _, err := app.authorizePlaylist(c, track.PlaylistID, minRole)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return track, playlist, nil
+}
+
+// meteredUserID is whose streaming quota a request counts against: the
+// logged in user, or the playlist owner for anonymous share links.
+func (app *Application) meteredUserID(c echo.Context, playlist *models.PlaylistsDBModel) string {
+	if userID, ok := app.currentUserID(c); ok {
+		return userID
+	}
+
+	return playlist.UserID
+}
+
+// currentUserID is the user a request acts for: the one set by the API
+// authentication, or the user logged in through the session.
+func (app *Application) currentUserID(c echo.Context) (string, bool) {
+	if userID, ok := c.Get("user_id").(string); ok && userID != "" {
+		return userID, true
+	}
+
+	if !app.alreadyLoggedIn(c) {
+		return "", false
+	}
+
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		return "", false
+	}
+
+	return userID, true
+}
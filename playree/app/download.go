@@ -0,0 +1,130 @@
+package app
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/blobstore"
+	"github.com/labstack/echo/v4"
+)
+
+// HandleDownloadPlaylist streams a ZIP archive of the playlist's audio and an
+// M3U playlist. Objects are copied from storage one by one into the response,
+// uncompressed, so nothing is buffered besides the copy buffer.
+func (app *Application) HandleDownloadPlaylist(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	userID := app.meteredUserID(c, playlist)
+
+	if err := app.checkStreamQuota(c, userID); err != nil {
+		return err
+	}
+
+	tracks, err := app.TrackStore.GetManyOrdered(
+		[]string{"track_key", "title", "artists", "duration_ms", "position"},
+		"position",
+		"playlist_id = ?", playlist.PlaylistID,
+	)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	// once the first byte is out errors can only be logged
+	defer app.recordStreamedBytes(c, userID)
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/zip")
+	c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{
+		"filename": sanitizeFileName(playlist.PlaylistName) + ".zip",
+	}))
+	c.Response().WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(c.Response())
+
+	m3u := strings.Builder{}
+	m3u.WriteString("#EXTM3U\n")
+
+	for i, track := range tracks {
+		name := fmt.Sprintf("%02d - %s - %s%s", i+1, sanitizeFileName(track.Artists), sanitizeFileName(track.Title), path.Ext(track.TrackKey))
+
+		if err := app.writeZipObject(c, zw, name, track.TrackKey); err != nil {
+			if errors.Is(err, blobstore.ErrNotExist) {
+				c.Logger().Warnf("skipping missing object %s", track.TrackKey)
+				continue
+			}
+
+			c.Logger().Error(err)
+			return nil
+		}
+
+		fmt.Fprintf(&m3u, "#EXTINF:%d,%s - %s\n%s\n", track.DurationMs/1000, track.Artists, track.Title, name)
+	}
+
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     sanitizeFileName(playlist.PlaylistName) + ".m3u",
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		c.Logger().Error(err)
+		return nil
+	}
+
+	if _, err := io.WriteString(w, m3u.String()); err != nil {
+		c.Logger().Error(err)
+		return nil
+	}
+
+	if err := zw.Close(); err != nil {
+		c.Logger().Error(err)
+	}
+
+	return nil
+}
+
+func (app *Application) writeZipObject(c echo.Context, zw *zip.Writer, name, key string) error {
+	obj, info, err := app.BlobStore.Get(c.Request().Context(), key, blobstore.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer obj.Close()
+
+	// audio is already compressed, deflating it again only costs CPU
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: info.LastModified,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, obj)
+	return err
+}
+
+// sanitizeFileName makes s safe to use as a file name on common file systems.
+func sanitizeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+
+		return r
+	}, s)
+
+	s = strings.Trim(strings.TrimSpace(s), ".")
+	if s == "" {
+		return "untitled"
+	}
+
+	return s
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// errorStatuses are the HTTP status codes of the errors in models/errors.go
+// that are the client's fault. Everything else is an internal error.
+var errorStatuses = []struct {
+	err    error
+	status int
+}{
+	{models.ErrInvalidRequest, http.StatusBadRequest},
+	{models.ErrInvalidAction, http.StatusBadRequest},
+	{models.ErrInvalidSpotifyLink, http.StatusBadRequest},
+	{models.ErrNoTracksToAdd, http.StatusBadRequest},
+	{models.ErrTooManyTracksToAdd, http.StatusBadRequest},
+	{models.ErrInvalidTrackOrder, http.StatusBadRequest},
+	{models.ErrInvalidRole, http.StatusBadRequest},
+	{models.ErrCannotShareWithOwner, http.StatusBadRequest},
+	{models.ErrInvalidSmartRule, http.StatusBadRequest},
+	{models.ErrInvalidPlaybackSource, http.StatusBadRequest},
+	{models.ErrInvalidPlayEvent, http.StatusBadRequest},
+	{models.ErrUserAlreadyExists, http.StatusBadRequest},
+	{models.ErrInvalidScope, http.StatusBadRequest},
+	{models.ErrPlaylistNotSyncable, http.StatusBadRequest},
+	{models.ErrInvalidWebhookURL, http.StatusBadRequest},
+	{models.ErrInvalidWebhookEvents, http.StatusBadRequest},
+	{models.ErrAuthenticationRequired, http.StatusUnauthorized},
+	{models.ErrInvalidAccessToken, http.StatusUnauthorized},
+	{models.ErrInsufficientScope, http.StatusForbidden},
+	{models.ErrTokenNotExists, http.StatusUnauthorized},
+	{models.ErrPlaylistAccessDenied, http.StatusForbidden},
+	{models.ErrTrackQuotaExceeded, http.StatusForbidden},
+	{models.ErrStorageQuotaExceeded, http.StatusForbidden},
+	{models.ErrUserNotExists, http.StatusNotFound},
+	{models.ErrPlaylistNotExists, http.StatusNotFound},
+	{models.ErrTrackNotExists, http.StatusNotFound},
+	{models.ErrJobNotExists, http.StatusNotFound},
+	{models.ErrWebhookNotExists, http.StatusNotFound},
+	{models.ErrSmartPlaylistNotExists, http.StatusNotFound},
+	{models.ErrPlaybackStateNotExists, http.StatusNotFound},
+	{models.ErrHLSNotExists, http.StatusNotFound},
+	{models.ErrInvalidPlaylistToken, http.StatusNotFound},
+	{gorm.ErrRecordNotFound, http.StatusNotFound},
+	{models.ErrBandwidthQuotaExceeded, http.StatusTooManyRequests},
+}
+
+// errorStatus is the HTTP status code of err, http.StatusInternalServerError
+// for errors that are not in errorStatuses.
+func errorStatus(err error) int {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+
+	for _, e := range errorStatuses {
+		if errors.Is(err, e.err) {
+			return e.status
+		}
+	}
+
+	return http.StatusInternalServerError
+}
+
+// httpError turns an error of a helper into the error a handler returns:
+// client errors become HTTP errors with their status, anything else is
+// logged.
+func (app *Application) httpError(c echo.Context, err error) error {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return echo.NewHTTPError(status, err)
+}
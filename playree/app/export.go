@@ -0,0 +1,276 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	playlistTokenExport = "export"
+	playlistTokenFeed   = "feed"
+	playlistTokenShare  = "share"
+)
+
+var playlistTokenKinds = map[string]bool{
+	playlistTokenExport: true,
+	playlistTokenFeed:   true,
+	playlistTokenShare:  true,
+}
+
+type exportsPage struct {
+	Playlist *models.PlaylistsDBModel
+	BaseURL  string
+	Tokens   []models.PlaylistTokenDBModel
+}
+
+func (app *Application) HandleExports(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	tokens, err := app.PlaylistTokenStore.GetManyOrdered(
+		"created_at DESC",
+		"playlist_id = ? AND kind IN ?", playlist.PlaylistID, []string{playlistTokenExport, playlistTokenFeed},
+	)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := c.Render(http.StatusOK, "exports.html", exportsPage{
+		Playlist: playlist,
+		BaseURL:  baseURL(c),
+		Tokens:   tokens,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (app *Application) HandleCreatePlaylistToken(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	kind := c.FormValue("kind")
+	if !playlistTokenKinds[kind] {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	token, err := newPlaylistToken()
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := app.PlaylistTokenStore.Create(models.PlaylistTokenDBModel{
+		Token:      token,
+		PlaylistID: playlist.PlaylistID,
+		UserID:     playlist.UserID,
+		Kind:       kind,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, playlistTokensPage(playlist, kind))
+}
+
+func (app *Application) HandleRevokePlaylistToken(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	token, err := app.PlaylistTokenStore.GetOne("token = ? AND playlist_id = ?", c.Param("token"), playlist.PlaylistID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, models.ErrInvalidPlaylistToken)
+		}
+
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := app.PlaylistTokenStore.Update(
+		map[string]any{"revoked_at": time.Now()},
+		"token = ? AND revoked_at IS NULL", token.Token,
+	); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, playlistTokensPage(playlist, token.Kind))
+}
+
+// playlistTokensPage is the page where tokens of kind are managed.
+func playlistTokensPage(playlist *models.PlaylistsDBModel, kind string) string {
+	if kind == playlistTokenShare {
+		return "/playlist/" + playlist.PlaylistID + "/sharing"
+	}
+
+	return "/playlist/" + playlist.PlaylistID + "/exports"
+}
+
+func (app *Application) HandleExportM3U8(c echo.Context) error {
+	playlist, tracks, err := app.getExportedTracks(c, playlistTokenExport)
+	if err != nil {
+		return err
+	}
+
+	m3u := strings.Builder{}
+	m3u.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&m3u, "#PLAYLIST:%s\n", playlist.PlaylistName)
+
+	for _, track := range tracks {
+		fmt.Fprintf(&m3u, "#EXTINF:%d,%s - %s\n%s\n", track.DurationMs/1000, track.Artists, track.Title, exportTrackURL(c, track))
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", sanitizeFileName(playlist.PlaylistName)+".m3u8"))
+
+	return c.Blob(http.StatusOK, "audio/x-mpegurl; charset=utf-8", []byte(m3u.String()))
+}
+
+type xspfPlaylist struct {
+	XMLName   xml.Name    `xml:"playlist"`
+	Version   string      `xml:"version,attr"`
+	Namespace string      `xml:"xmlns,attr"`
+	Title     string      `xml:"title"`
+	Tracks    []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title"`
+	Creator  string `xml:"creator,omitempty"`
+	Album    string `xml:"album,omitempty"`
+	TrackNum int    `xml:"trackNum"`
+	Duration int64  `xml:"duration,omitempty"`
+}
+
+func (app *Application) HandleExportXSPF(c echo.Context) error {
+	playlist, tracks, err := app.getExportedTracks(c, playlistTokenExport)
+	if err != nil {
+		return err
+	}
+
+	xspf := xspfPlaylist{
+		Version:   "1",
+		Namespace: "http://xspf.org/ns/0/",
+		Title:     playlist.PlaylistName,
+	}
+
+	for i, track := range tracks {
+		xspf.Tracks = append(xspf.Tracks, xspfTrack{
+			Location: exportTrackURL(c, track),
+			Title:    track.Title,
+			Creator:  track.Artists,
+			Album:    track.Album,
+			TrackNum: i + 1,
+			Duration: track.DurationMs,
+		})
+	}
+
+	data, err := xml.MarshalIndent(xspf, "", "  ")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", sanitizeFileName(playlist.PlaylistName)+".xspf"))
+
+	return c.Blob(http.StatusOK, "application/xspf+xml; charset=utf-8", append([]byte(xml.Header), data...))
+}
+
+// HandleExportStreamTrack serves a track to an external player. Export and
+// feed tokens of the track's playlist are accepted, and the bytes count
+// against the quota of the playlist owner.
+func (app *Application) HandleExportStreamTrack(c echo.Context) error {
+	token, err := app.getPlaylistToken(c, "")
+	if err != nil {
+		return err
+	}
+
+	if token.Kind == playlistTokenShare {
+		return echo.NewHTTPError(http.StatusNotFound, models.ErrInvalidPlaylistToken)
+	}
+
+	track, err := app.TrackStore.GetOne("track_id = ? AND playlist_id = ?", c.Param("track_id"), token.PlaylistID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, models.ErrTrackNotExists)
+		}
+
+		c.Logger().Error(err)
+		return err
+	}
+
+	return app.serveMeteredObject(c, token.UserID, track.TrackKey)
+}
+
+// getPlaylistToken checks the token route parameter. An empty kind accepts
+// tokens of every kind.
+func (app *Application) getPlaylistToken(c echo.Context, kind string) (*models.PlaylistTokenDBModel, error) {
+	token, err := app.PlaylistTokenStore.GetOne("token = ? AND revoked_at IS NULL", c.Param("token"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, models.ErrInvalidPlaylistToken)
+		}
+
+		c.Logger().Error(err)
+		return nil, err
+	}
+
+	if kind != "" && token.Kind != kind {
+		return nil, echo.NewHTTPError(http.StatusNotFound, models.ErrInvalidPlaylistToken)
+	}
+
+	return token, nil
+}
+
+func (app *Application) getExportedTracks(c echo.Context, kind string) (*models.PlaylistsDBModel, []models.TrackDBModel, error) {
+	token, err := app.getPlaylistToken(c, kind)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	playlist, err := app.PlaylistStore.GetOne("playlist_id = ?", token.PlaylistID)
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, nil, err
+	}
+
+	tracks, err := app.TrackStore.GetManyOrdered(
+		[]string{"track_id", "title", "artists", "album", "duration_ms", "position", "track_key", "audio_size_bytes", "inserted_at"},
+		"position",
+		"playlist_id = ?", playlist.PlaylistID,
+	)
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, nil, err
+	}
+
+	return playlist, tracks, nil
+}
+
+func exportTrackURL(c echo.Context, track models.TrackDBModel) string {
+	return fmt.Sprintf("%s/export/%s/tracks/%s", baseURL(c), c.Param("token"), track.TrackID)
+}
+
+func baseURL(c echo.Context) string {
+	return c.Scheme() + "://" + c.Request().Host
+}
+
+func newPlaylistToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+)
+
+type rssFeed struct {
+	XMLName  xml.Name   `xml:"rss"`
+	Version  string     `xml:"version,attr"`
+	ITunesNS string     `xml:"xmlns:itunes,attr"`
+	Channel  rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Image       *rssImage    `xml:"image,omitempty"`
+	ITunesImage *itunesImage `xml:"itunes:image,omitempty"`
+	ITunesType  string       `xml:"itunes:type"`
+	ITunesBlock string       `xml:"itunes:block"`
+	Items       []rssItem    `xml:"item"`
+}
+
+type rssImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title          string       `xml:"title"`
+	Description    string       `xml:"description"`
+	GUID           rssGUID      `xml:"guid"`
+	PubDate        string       `xml:"pubDate"`
+	Enclosure      rssEnclosure `xml:"enclosure"`
+	ITunesDuration int64        `xml:"itunes:duration"`
+	ITunesEpisode  int          `xml:"itunes:episode"`
+	ITunesAuthor   string       `xml:"itunes:author"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// HandleFeed publishes a playlist as a private podcast. The channel is marked
+// serial and every track is an episode, so podcast apps play it in playlist
+// order; pub dates are spaced a second apart for apps that sort by date.
+// Podcast apps poll feeds often, so enclosures are described from the tracks
+// table, tracks are stored as MP3. Tracks stored before their audio size was
+// recorded are looked up in the blob store once.
+func (app *Application) HandleFeed(c echo.Context) error {
+	playlist, tracks, err := app.getExportedTracks(c, playlistTokenFeed)
+	if err != nil {
+		return err
+	}
+
+	feed := rssFeed{
+		Version:  "2.0",
+		ITunesNS: "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel: rssChannel{
+			Title:       playlist.PlaylistName,
+			Link:        baseURL(c) + "/playlist/" + playlist.PlaylistID,
+			Description: fmt.Sprintf("%s, %d tracks on Playree", playlist.PlaylistName, len(tracks)),
+			ITunesType:  "serial",
+			ITunesBlock: "Yes",
+		},
+	}
+
+	if playlist.ArtworkURL != "" {
+		feed.Channel.Image = &rssImage{
+			URL:   playlist.ArtworkURL,
+			Title: playlist.PlaylistName,
+			Link:  feed.Channel.Link,
+		}
+		feed.Channel.ITunesImage = &itunesImage{Href: playlist.ArtworkURL}
+	}
+
+	for i, track := range tracks {
+		if track.AudioSizeBytes == 0 {
+			track.AudioSizeBytes = app.recordAudioSize(c, track)
+		}
+
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       fmt.Sprintf("%s - %s", track.Artists, track.Title),
+			Description: track.Album,
+			GUID:        rssGUID{Value: track.TrackID},
+			PubDate:     track.InsertedAt.Add(time.Duration(i) * time.Second).UTC().Format(time.RFC1123Z),
+			Enclosure: rssEnclosure{
+				URL:    exportTrackURL(c, track),
+				Length: track.AudioSizeBytes,
+				Type:   "audio/mpeg",
+			},
+			ITunesDuration: track.DurationMs / 1000,
+			ITunesEpisode:  i + 1,
+			ITunesAuthor:   track.Artists,
+		})
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	c.Response().Header().Set("Cache-Control", "private, max-age=300")
+
+	return c.Blob(http.StatusOK, "application/rss+xml; charset=utf-8", append([]byte(xml.Header), data...))
+}
+
+// recordAudioSize stats the audio object of a track and saves its size, so
+// the blob store is only asked once per track.
+func (app *Application) recordAudioSize(c echo.Context, track models.TrackDBModel) int64 {
+	info, err := app.BlobStore.Stat(c.Request().Context(), track.TrackKey)
+	if err != nil {
+		c.Logger().Error(err)
+		return 0
+	}
+
+	if err := app.TrackStore.Update(map[string]any{"audio_size_bytes": info.Size}, "track_id = ?", track.TrackID); err != nil {
+		c.Logger().Error(err)
+	}
+
+	return info.Size
+}
@@ -0,0 +1,278 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/NikhilSharmaWe/playree/playree/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcWriteMethods need a read-write access token.
+var grpcWriteMethods = map[string]bool{
+	proto.PlayreeService_CreatePlaylist_FullMethodName: true,
+}
+
+// grpcCodes are the gRPC codes of the HTTP statuses of errorStatus.
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:   codes.InvalidArgument,
+	http.StatusUnauthorized: codes.Unauthenticated,
+	http.StatusForbidden:    codes.PermissionDenied,
+	http.StatusNotFound:     codes.NotFound,
+
+	http.StatusTooManyRequests: codes.ResourceExhausted,
+}
+
+// grpcUserKey is the context key of the user the token interceptors
+// authenticated.
+type grpcUserKey struct{}
+
+// RunGRPCServer serves PlayreeService on GRPCAddr. Calls are authenticated
+// with personal access tokens, like the JSON API.
+func (app *Application) RunGRPCServer() error {
+	ln, err := net.Listen("tcp", app.GRPCAddr)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(app.grpcUnaryAuth),
+		grpc.StreamInterceptor(app.grpcStreamAuth),
+	)
+	proto.RegisterPlayreeServiceServer(server, NewPlayreeServer(app))
+
+	return server.Serve(ln)
+}
+
+func (app *Application) grpcUnaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := app.grpcAuthenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (app *Application) grpcStreamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := app.grpcAuthenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream carries the context of grpcAuthenticate.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcAuthenticate checks the "authorization: Bearer <token>" metadata of a
+// call to method and returns ctx with the user of the token.
+func (app *Application) grpcAuthenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, grpcError(models.ErrAuthenticationRequired)
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, grpcError(models.ErrAuthenticationRequired)
+	}
+
+	accessToken, err := app.authenticateAccessToken(strings.TrimSpace(token))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	if grpcWriteMethods[method] && accessToken.Scope != ScopeReadWrite {
+		return nil, grpcError(models.ErrInsufficientScope)
+	}
+
+	return context.WithValue(ctx, grpcUserKey{}, accessToken.UserID), nil
+}
+
+// grpcError turns an error into a gRPC status with the code of its HTTP
+// status. Internal errors are logged and not shown.
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if err := status.FromContextError(err); err.Code() != codes.Unknown {
+		return err.Err()
+	}
+
+	code, ok := grpcCodes[errorStatus(err)]
+	if !ok {
+		log.Println("ERROR: GRPC: ", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	return status.Error(code, err.Error())
+}
+
+type PlayreeServer struct {
+	app *Application
+	proto.UnimplementedPlayreeServiceServer
+}
+
+func NewPlayreeServer(app *Application) *PlayreeServer {
+	return &PlayreeServer{
+		app: app,
+	}
+}
+
+func (s *PlayreeServer) ListPlaylists(ctx context.Context, req *proto.ListPlaylistsRequest) (*proto.ListPlaylistsResponse, error) {
+	limit, offset := int(req.Limit), int(req.Offset)
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	if limit < 0 || limit > maxPageSize || offset < 0 {
+		return nil, grpcError(models.ErrInvalidRequest)
+	}
+
+	playlists, err := s.app.userPlaylists(grpcUserID(ctx), limit+1, offset)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &proto.ListPlaylistsResponse{}
+	if len(playlists) > limit {
+		playlists = playlists[:limit]
+		resp.NextOffset = int32(offset + limit)
+	}
+
+	for _, playlist := range playlists {
+		resp.Playlists = append(resp.Playlists, playlistToProto(&playlist.PlaylistsDBModel, playlist.Role, 0))
+	}
+
+	return resp, nil
+}
+
+func (s *PlayreeServer) GetPlaylist(ctx context.Context, req *proto.GetPlaylistRequest) (*proto.GetPlaylistResponse, error) {
+	playlist, role, err := s.app.authorizeUserPlaylist(grpcUserID(ctx), req.PlaylistId, RoleViewer)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	tracks, err := s.app.TrackStore.GetManyOrdered(apiTrackFields, "position", "playlist_id = ?", playlist.PlaylistID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &proto.GetPlaylistResponse{
+		Playlist: playlistToProto(playlist, role, int64(len(tracks))),
+	}
+
+	for _, track := range tracks {
+		resp.Tracks = append(resp.Tracks, trackToProto(&track))
+	}
+
+	return resp, nil
+}
+
+func (s *PlayreeServer) CreatePlaylist(ctx context.Context, req *proto.CreatePlaylistRequest) (*proto.Job, error) {
+	spotifyPlaylistID, err := spotifyPlaylistID(strings.TrimSpace(req.SpotifyPlaylist))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	job, err := s.app.startCreateJob(ctx, grpcUserID(ctx), spotifyPlaylistID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return jobToProto(job), nil
+}
+
+// WatchJob sends the job, and once more when it is done or failed: jobs only
+// change state once.
+func (s *PlayreeServer) WatchJob(req *proto.WatchJobRequest, stream proto.PlayreeService_WatchJobServer) error {
+	job, err := s.app.userJob(grpcUserID(stream.Context()), req.JobId)
+	if err != nil {
+		return grpcError(err)
+	}
+
+	if err := stream.Send(jobToProto(job)); err != nil {
+		return err
+	}
+
+	if job.Status != jobPending {
+		return nil
+	}
+
+	job, err = s.app.waitForJob(stream.Context(), job.JobID)
+	if err != nil {
+		return grpcError(err)
+	}
+
+	return stream.Send(jobToProto(job))
+}
+
+// grpcUserID is the user grpcAuthenticate found for the call.
+func grpcUserID(ctx context.Context) string {
+	userID, _ := ctx.Value(grpcUserKey{}).(string)
+	return userID
+}
+
+func playlistToProto(playlist *models.PlaylistsDBModel, role string, trackCount int64) *proto.Playlist {
+	return &proto.Playlist{
+		PlaylistId:        playlist.PlaylistID,
+		PlaylistName:      playlist.PlaylistName,
+		UserId:            playlist.UserID,
+		ArtworkUrl:        playlist.ArtworkURL,
+		SpotifyPlaylistId: playlist.SpotifyPlaylistID,
+		Role:              role,
+		TrackCount:        trackCount,
+	}
+}
+
+func trackToProto(track *models.TrackDBModel) *proto.Track {
+	return &proto.Track{
+		TrackId:        track.TrackID,
+		Title:          track.Title,
+		Artists:        track.Artists,
+		Album:          track.Album,
+		DurationMs:     track.DurationMs,
+		Position:       int32(track.Position),
+		SpotifyTrackId: track.SpotifyTrackID,
+		SizeBytes:      track.SizeBytes,
+		AddedBy:        track.AddedBy,
+	}
+}
+
+func jobToProto(job *models.JobDBModel) *proto.Job {
+	resp := &proto.Job{
+		JobId:      job.JobID,
+		PlaylistId: job.PlaylistID,
+		UserId:     job.UserID,
+		Kind:       job.Kind,
+		Status:     job.Status,
+		Error:      job.Error,
+		TrackCount: int32(job.TrackCount),
+		CreatedAt:  timestamppb.New(job.CreatedAt),
+	}
+
+	if job.FinishedAt != nil {
+		resp.FinishedAt = timestamppb.New(*job.FinishedAt)
+	}
+
+	return resp
+}
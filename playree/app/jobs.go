@@ -0,0 +1,447 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/NikhilSharmaWe/playree/playree/store"
+	"github.com/NikhilSharmaWe/rabbitmq"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/gorm"
+)
+
+// Job kinds, a job creates a playlist, adds tracks to one, or adds the new
+// tracks of its Spotify playlist.
+const (
+	jobCreate = "create"
+	jobAdd    = "add"
+	jobSync   = "sync"
+)
+
+const (
+	jobPending = "pending"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// errJobFinished rolls back the handling of a response whose job has been
+// finished in the meantime.
+var errJobFinished = errors.New("job already finished")
+
+// jobPollInterval is how often waitForJob checks a job another instance may
+// be finishing.
+const jobPollInterval = 2 * time.Second
+
+// CreatePlaylistResponseQueue is the durable queue playlist_creator answers
+// jobs on. All instances consume it, a job is finished by whichever gets the
+// response, even if the instance that started it has restarted.
+const CreatePlaylistResponseQueue = "create-playlist-response"
+
+// publishJob records a job on playlist, nil for create jobs, and sends it to
+// playlist_creator. The response comes back on CreatePlaylistResponseQueue
+// and is handled by HandleCreatePlaylistResponse. A job that cannot be sent is
+// failed right away, nothing would ever finish it.
+func (app *Application) publishJob(ctx context.Context, job *models.JobDBModel, playlist *models.PlaylistsDBModel, tracks []*models.Track) error {
+	data, err := json.Marshal(tracks)
+	if err != nil {
+		return err
+	}
+
+	job.Status = jobPending
+	job.TrackCount = len(tracks)
+	job.Tracks = string(data)
+	job.CreatedAt = time.Now()
+
+	if err := app.JobStore.Create(*job); err != nil {
+		return err
+	}
+
+	app.emitJobEvent(EventJobStarted, job, playlist)
+
+	if err := app.sendJob(ctx, job, tracks); err != nil {
+		finished, finishErr := finishJob(app.JobStore, job, err)
+		if finishErr != nil {
+			log.Println("ERROR: FAILING UNSENT JOB: ", finishErr)
+		}
+
+		if finished {
+			app.notifyJobWaiters(job.JobID)
+			app.emitJobEvent(EventJobFailed, job, playlist)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (app *Application) sendJob(ctx context.Context, job *models.JobDBModel, tracks []*models.Track) error {
+	body, err := json.Marshal(models.CreatePlaylistRequest{
+		JobID:             job.JobID,
+		PlayreePlaylistID: job.PlaylistID,
+		Tracks:            tracks,
+	})
+	if err != nil {
+		return err
+	}
+
+	rabbitMQClient, err := rabbitmq.NewRabbitMQClient(app.PublishingConn)
+	if err != nil {
+		return err
+	}
+
+	defer rabbitMQClient.Close()
+
+	return rabbitMQClient.Send(ctx, "create-playlist", "create-playlist-request", amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         body,
+		ReplyTo:      CreatePlaylistResponseQueue,
+		DeliveryMode: amqp.Persistent,
+	})
+}
+
+// startCreateJob starts a job that imports a Spotify playlist for userID.
+// The import counts against the user's quotas.
+func (app *Application) startCreateJob(ctx context.Context, userID, spotifyPlaylistID string) (*models.JobDBModel, error) {
+	client, err := app.spotifyClient(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tracksData, playlistName, artworkURL, err := getNameAndTracksFromPlaylist(client, spotifyPlaylistID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := app.getUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.checkCreateQuota(usage, tracksData); err != nil {
+		return nil, err
+	}
+
+	job := &models.JobDBModel{
+		JobID:        uuid.NewString(),
+		PlaylistID:   uuid.NewString(),
+		UserID:       userID,
+		Kind:         jobCreate,
+		PlaylistName: playlistName,
+		ArtworkURL:   artworkURL,
+
+		SpotifyPlaylistID: spotifyPlaylistID,
+	}
+
+	if err := app.publishJob(ctx, job, nil, tracksData); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// startAddJob starts a job that appends tracks to a playlist on behalf of
+// userID. The tracks count against the quotas of the playlist owner.
+func (app *Application) startAddJob(ctx context.Context, playlist *models.PlaylistsDBModel, userID string, tracks []*models.Track) (*models.JobDBModel, error) {
+	if len(tracks) == 0 {
+		return nil, models.ErrNoTracksToAdd
+	}
+
+	if len(tracks) > maxAddTracks {
+		return nil, models.ErrTooManyTracksToAdd
+	}
+
+	usage, err := app.getUsage(playlist.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.checkCreateQuota(usage, tracks); err != nil {
+		return nil, err
+	}
+
+	job := &models.JobDBModel{
+		JobID:      uuid.NewString(),
+		PlaylistID: playlist.PlaylistID,
+		UserID:     userID,
+		Kind:       jobAdd,
+	}
+
+	if err := app.publishJob(ctx, job, playlist, tracks); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// waitForJob blocks until a job is done or failed, or ctx ends. Jobs finished
+// by this instance wake it up right away, the others are polled.
+func (app *Application) waitForJob(ctx context.Context, jobID string) (*models.JobDBModel, error) {
+	ch := make(chan struct{}, 1)
+
+	app.jobWaitersMu.Lock()
+	app.jobWaiters[jobID] = append(app.jobWaiters[jobID], ch)
+	app.jobWaitersMu.Unlock()
+
+	defer func() {
+		app.jobWaitersMu.Lock()
+		defer app.jobWaitersMu.Unlock()
+
+		waiters := app.jobWaiters[jobID]
+		for i, waiter := range waiters {
+			if waiter == ch {
+				waiters = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+
+		if len(waiters) == 0 {
+			delete(app.jobWaiters, jobID)
+		} else {
+			app.jobWaiters[jobID] = waiters
+		}
+	}()
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := app.JobStore.GetOne("job_id = ?", jobID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, models.ErrJobNotExists
+			}
+
+			return nil, err
+		}
+
+		if job.Status != jobPending {
+			return job, nil
+		}
+
+		select {
+		case <-ch:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return job, ctx.Err()
+		}
+	}
+}
+
+func (app *Application) notifyJobWaiters(jobID string) {
+	app.jobWaitersMu.Lock()
+	defer app.jobWaitersMu.Unlock()
+
+	for _, ch := range app.jobWaiters[jobID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// HandleCreatePlaylistResponse finishes a job with the response of
+// playlist_creator: the playlist of a create job is inserted, the tracks of
+// an add or sync job are appended. Nothing depends on the browser that
+// started the job, it only waits with waitForJob. An error means the job is
+// still pending and the response has to be handled again; a job that failed
+// is finished and logged.
+func (app *Application) HandleCreatePlaylistResponse(resp models.RabbitMQCreatePlaylistResponse) error {
+	// responses of older playlist_creator versions carry no job id
+	jobID := resp.JobID
+	if jobID == "" {
+		jobID = resp.PlayreePlaylistID
+	}
+
+	job, err := app.JobStore.GetOne("job_id = ?", jobID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %s", models.ErrJobNotExists, jobID)
+		}
+
+		return err
+	}
+
+	// redelivered response
+	if job.Status != jobPending {
+		return nil
+	}
+
+	// The tracks are stored in the transaction that finishes the job. They
+	// are added in a nested transaction, so a job whose tracks cannot be
+	// stored is still failed. A response that is handled twice at once
+	// finds the job finished and rolls back.
+	var jobErr error
+
+	err = app.JobStore.DB().Transaction(func(tx *gorm.DB) error {
+		switch {
+		case !resp.Success:
+			jobErr = errors.New(resp.Error)
+		case job.Kind == jobCreate:
+			jobErr = app.createPlaylistFromJob(tx, job, resp.Tracks)
+		default:
+			jobErr = app.addCreatedTracks(tx, job, resp.Tracks)
+		}
+
+		finished, err := finishJob(store.NewJobStore(tx), job, jobErr)
+		if err != nil {
+			return err
+		}
+
+		if !finished {
+			return errJobFinished
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errJobFinished) {
+			return nil
+		}
+
+		return err
+	}
+
+	app.notifyJobWaiters(job.JobID)
+	app.emitFinishedJobEvents(job)
+
+	if jobErr != nil {
+		log.Printf("ERROR: JOB %s FAILED: %s", job.JobID, jobErr)
+	}
+
+	return nil
+}
+
+// finishJob marks a pending job as done, or as failed with jobErr. It reports
+// false when the job was finished already.
+func finishJob(jobStore store.JobStore, job *models.JobDBModel, jobErr error) (bool, error) {
+	now := time.Now()
+
+	job.Status, job.Error, job.FinishedAt = jobDone, "", &now
+	if jobErr != nil {
+		job.Status, job.Error = jobFailed, jobErr.Error()
+	}
+
+	return jobStore.UpdatePending(job.JobID, map[string]any{
+		"status":      job.Status,
+		"error":       job.Error,
+		"finished_at": now,
+	})
+}
+
+// emitFinishedJobEvents reports a finished job to webhooks, a done sync job
+// also as playlist.synced.
+func (app *Application) emitFinishedJobEvents(job *models.JobDBModel) {
+	// the playlist may be gone, e.g. after a failed create job, the events
+	// are sent without it then
+	playlist, err := app.PlaylistStore.GetOne("playlist_id = ?", job.PlaylistID)
+	if err != nil {
+		playlist = nil
+	}
+
+	if job.Status == jobFailed {
+		app.emitJobEvent(EventJobFailed, job, playlist)
+		return
+	}
+
+	app.emitJobEvent(EventJobFinished, job, playlist)
+
+	if job.Kind == jobSync {
+		app.emitJobEvent(EventPlaylistSynced, job, playlist)
+	}
+}
+
+func (app *Application) createPlaylistFromJob(tx *gorm.DB, job *models.JobDBModel, created []models.CreatedTrack) error {
+	tracksData := []*models.Track{}
+	if err := json.Unmarshal([]byte(job.Tracks), &tracksData); err != nil {
+		return err
+	}
+
+	return app.handleAfterPlaylistCreated(tx, &models.PlaylistsDBModel{
+		PlaylistID:   job.PlaylistID,
+		PlaylistName: job.PlaylistName,
+		ArtworkURL:   job.ArtworkURL,
+		UserID:       job.UserID,
+
+		SpotifyPlaylistID: job.SpotifyPlaylistID,
+	}, tracksData, created)
+}
+
+// addCreatedTracks appends the tracks of an add or sync job to its playlist. The
+// playlist row is locked, so concurrent jobs of several editors get distinct
+// positions.
+func (app *Application) addCreatedTracks(tx *gorm.DB, job *models.JobDBModel, created []models.CreatedTrack) error {
+	tracksData := []*models.Track{}
+	if err := json.Unmarshal([]byte(job.Tracks), &tracksData); err != nil {
+		return err
+	}
+
+	return tx.Transaction(func(tx *gorm.DB) error {
+		trackStore := store.NewTrackStore(tx)
+
+		if err := lockPlaylist(tx, job.PlaylistID); err != nil {
+			return err
+		}
+
+		next, err := trackStore.NextPosition(job.PlaylistID)
+		if err != nil {
+			return err
+		}
+
+		tracks, err := createdTrackModels(job.PlaylistID, job.UserID, tracksData, created, next)
+		if err != nil {
+			return err
+		}
+
+		if len(tracks) == 0 {
+			return nil
+		}
+
+		return trackStore.CreateInBatches(tracks)
+	})
+}
+
+// createdTrackModels builds the rows of the tracks playlist_creator created
+// out of tracksData. Tracks that could not be created leave no gap: the rows
+// get consecutive positions starting at first, in request order.
+func createdTrackModels(playlistID, addedBy string, tracksData []*models.Track, created []models.CreatedTrack, first int) ([]models.TrackDBModel, error) {
+	created = append([]models.CreatedTrack(nil), created...)
+	sort.Slice(created, func(i, j int) bool {
+		return created[i].Position < created[j].Position
+	})
+
+	tracks := []models.TrackDBModel{}
+	for i, createdTrack := range created {
+		if createdTrack.Position < 0 || createdTrack.Position >= len(tracksData) {
+			return nil, fmt.Errorf("created track %s has invalid position %d", createdTrack.TrackID, createdTrack.Position)
+		}
+
+		track := tracksData[createdTrack.Position]
+
+		tracks = append(tracks, models.TrackDBModel{
+			TrackID:        createdTrack.TrackID,
+			PlaylistID:     playlistID,
+			TrackKey:       createdTrack.TrackKey,
+			Title:          track.Name,
+			Artists:        track.Artists,
+			Album:          track.Album,
+			DurationMs:     track.DurationMs,
+			Position:       first + i,
+			VideoID:        createdTrack.VideoID,
+			HLSKey:         createdTrack.HLSKey,
+			SpotifyTrackID: track.SpotifyTrackID,
+			SizeBytes:      createdTrack.SizeBytes,
+			AudioSizeBytes: createdTrack.AudioSizeBytes,
+			AddedBy:        addedBy,
+		})
+	}
+
+	return tracks, nil
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/NikhilSharmaWe/playree/playree/store"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type managePage struct {
+	Playlist *models.PlaylistsDBModel
+	Role     string
+	Tracks   []models.TrackDBModel
+	// Usernames maps the users who added tracks to their names.
+	Usernames map[string]string
+}
+
+type reorderRequest struct {
+	TrackIDs []string `json:"track_ids"`
+}
+
+func (app *Application) HandleManagePlaylist(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	tracks, err := app.TrackStore.GetManyOrdered(
+		[]string{"track_id", "title", "artists", "album", "duration_ms", "position", "added_by"},
+		"position",
+		"playlist_id = ?", playlist.PlaylistID,
+	)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	addedBy := []string{}
+	for _, track := range tracks {
+		if track.AddedBy != "" {
+			addedBy = append(addedBy, track.AddedBy)
+		}
+	}
+
+	users, err := app.UserStore.GetMany("user_id IN ?", addedBy)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	usernames := make(map[string]string)
+	for _, user := range users {
+		usernames[user.UserID] = user.Username
+	}
+
+	if err := c.Render(http.StatusOK, "manage.html", managePage{
+		Playlist:  playlist,
+		Role:      getPlaylistRole(c),
+		Tracks:    tracks,
+		Usernames: usernames,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (app *Application) HandleRenamePlaylist(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	if err := app.renamePlaylist(playlist, c.FormValue("playlist_name")); err != nil {
+		return app.httpError(c, err)
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/playlist/"+playlist.PlaylistID+"/manage")
+}
+
+func (app *Application) HandleDeletePlaylist(c echo.Context) error {
+	if err := app.deletePlaylist(c, getPlaylist(c)); err != nil {
+		return app.httpError(c, err)
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/my-playlists")
+}
+
+func (app *Application) HandleRemoveTrack(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	if err := app.removeTrack(c, playlist, c.Param("track_id")); err != nil {
+		return app.httpError(c, err)
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/playlist/"+playlist.PlaylistID+"/manage")
+}
+
+func (app *Application) renamePlaylist(playlist *models.PlaylistsDBModel, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.ErrInvalidRequest
+	}
+
+	if err := app.PlaylistStore.Update(map[string]any{"playlist_name": name}, "playlist_id = ?", playlist.PlaylistID); err != nil {
+		return err
+	}
+
+	playlist.PlaylistName = name
+
+	return nil
+}
+
+// deletePlaylist deletes the playlist with its tracks and then its objects.
+// Objects that fail to delete are left to the reconciliation job.
+func (app *Application) deletePlaylist(c echo.Context, playlist *models.PlaylistsDBModel) error {
+	if err := app.PlaylistStore.Delete("playlist_id = ?", playlist.PlaylistID); err != nil {
+		return err
+	}
+
+	if err := app.deleteObjects(c.Request().Context(), playlist.PlaylistID+"/"); err != nil {
+		c.Logger().Error(err)
+	}
+
+	return nil
+}
+
+// removeTrack removes a track from its playlist, closes the gap in the
+// positions and deletes the track's objects. The playlist row is locked like
+// in addCreatedTracks, so removals, reorders and added tracks do not mix up
+// positions.
+func (app *Application) removeTrack(c echo.Context, playlist *models.PlaylistsDBModel, trackID string) error {
+	var track *models.TrackDBModel
+
+	if err := app.TrackStore.DB().Transaction(func(tx *gorm.DB) error {
+		trackStore := store.NewTrackStore(tx)
+
+		if err := lockPlaylist(tx, playlist.PlaylistID); err != nil {
+			return err
+		}
+
+		var err error
+		track, err = trackStore.GetOne("track_id = ? AND playlist_id = ?", trackID, playlist.PlaylistID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrTrackNotExists
+			}
+
+			return err
+		}
+
+		if err := trackStore.Delete("track_id = ?", track.TrackID); err != nil {
+			return err
+		}
+
+		return trackStore.Update(
+			map[string]any{"position": gorm.Expr("position - 1")},
+			"playlist_id = ? AND position > ?", playlist.PlaylistID, track.Position,
+		)
+	}); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	if err := app.BlobStore.Delete(ctx, track.TrackKey); err != nil {
+		c.Logger().Error(err)
+	}
+
+	// the HLS rendition lives below the track key without its extension
+	if err := app.deleteObjects(ctx, strings.TrimSuffix(track.TrackKey, path.Ext(track.TrackKey))+"/"); err != nil {
+		c.Logger().Error(err)
+	}
+
+	return nil
+}
+
+// HandleReorderTracks sets the positions of a playlist's tracks to the order
+// of track_ids, which has to list every track exactly once. The tracks are
+// checked with the playlist row locked, so tracks added or removed meanwhile
+// fail the check instead of getting duplicate positions.
+func (app *Application) HandleReorderTracks(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	var req reorderRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	if err := app.TrackStore.DB().Transaction(func(tx *gorm.DB) error {
+		trackStore := store.NewTrackStore(tx)
+
+		if err := lockPlaylist(tx, playlist.PlaylistID); err != nil {
+			return err
+		}
+
+		tracks, err := trackStore.GetMany([]string{"track_id"}, "playlist_id = ?", playlist.PlaylistID)
+		if err != nil {
+			return err
+		}
+
+		if !sameTracks(tracks, req.TrackIDs) {
+			return models.ErrInvalidTrackOrder
+		}
+
+		for position, trackID := range req.TrackIDs {
+			if err := trackStore.Update(map[string]any{"position": position}, "track_id = ?", trackID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return app.httpError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// sameTracks reports whether trackIDs lists every track exactly once.
+func sameTracks(tracks []models.TrackDBModel, trackIDs []string) bool {
+	if len(trackIDs) != len(tracks) {
+		return false
+	}
+
+	remaining := make(map[string]bool)
+	for _, track := range tracks {
+		remaining[track.TrackID] = true
+	}
+
+	for _, trackID := range trackIDs {
+		if !remaining[trackID] {
+			return false
+		}
+
+		delete(remaining, trackID)
+	}
+
+	return true
+}
+
+// lockPlaylist locks the playlist row until the transaction tx ends. Every
+// change to the positions of a playlist's tracks takes this lock first.
+func lockPlaylist(tx *gorm.DB, playlistID string) error {
+	if _, err := store.NewPlaylistStore(tx).LockOne("playlist_id = ?", playlistID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ErrPlaylistNotExists
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (app *Application) deleteObjects(ctx context.Context, prefix string) error {
+	objects, err := app.BlobStore.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		if err := app.BlobStore.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,12 +2,10 @@ package app
 
 import (
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/NikhilSharmaWe/playree/playree/models"
-	"github.com/NikhilSharmaWe/playree/playree/store"
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 )
 
 func (app *Application) CreateSessionMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
@@ -42,6 +40,31 @@ func (app *Application) IfNotLogined(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// BearerAuth authenticates requests that carry a personal access token as
+// "Authorization: Bearer <token>". Requests without one fall through to the
+// session, like IfNotLogined does for pages.
+func (app *Application) BearerAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok {
+			return next(c)
+		}
+
+		accessToken, err := app.authenticateAccessToken(strings.TrimSpace(token))
+		if err != nil {
+			return app.httpError(c, err)
+		}
+
+		if !scopeAllows(accessToken.Scope, c.Request().Method) {
+			return echo.NewHTTPError(http.StatusForbidden, models.ErrInsufficientScope)
+		}
+
+		c.Set("user_id", accessToken.UserID)
+
+		return next(c)
+	}
+}
+
 func (app *Application) UpdateSpotifyTokenIfExpired(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		userID, err := getContext(c, "user_id")
@@ -76,38 +99,3 @@ func (app *Application) UpdateSpotifyTokenIfExpired(next echo.HandlerFunc) echo.
 		return next(c)
 	}
 }
-
-func (app *Application) UpdateTrackURIsIfAboutToExpire(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		playlistID := c.Param("playlist_id")
-		update, err := app.shouldUpdatePresignedURIs(playlistID)
-		if err != nil {
-			c.Logger().Error(err)
-			return err
-		}
-
-		if update {
-			data, err := app.generatePresignedURIsForPlaylistTracks(playlistID)
-			if err != nil {
-				c.Logger().Error(err)
-				return err
-			}
-
-			db := app.TrackStore.DB()
-			return db.Transaction(func(tx *gorm.DB) error {
-				trackStore := store.NewTrackStore(db)
-
-				for key, uri := range data {
-					if err := trackStore.Update(map[string]any{"track_uri": uri, "inserted_at": time.Now()}, "track_key = ?", key); err != nil {
-						c.Logger().Error(err)
-						return err
-					}
-				}
-
-				return nil
-			})
-		}
-
-		return next(c)
-	}
-}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Kinds of playlists a playback state can point at.
+const (
+	playbackPlaylist = "playlist"
+	playbackSmart    = "smart"
+)
+
+type playbackRequest struct {
+	Source     string `json:"source"`
+	PlaylistID string `json:"playlist_id"`
+	TrackID    string `json:"track_id"`
+	TrackIndex int    `json:"track_index"`
+	PositionMs int64  `json:"position_ms"`
+	Device     string `json:"device"`
+}
+
+// playbackResume is a saved playback state with what is needed to show and
+// open it.
+type playbackResume struct {
+	models.PlaybackStateDBModel
+	PlaylistName string `json:"playlist_name"`
+	Title        string `json:"title"`
+	Artists      string `json:"artists"`
+	URL          string `json:"resume_url"`
+}
+
+// HandleSavePlayback stores the heartbeat of a player: the playlist, the
+// track and the position the user is at.
+func (app *Application) HandleSavePlayback(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	var req playbackRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	track, err := app.TrackStore.GetOne("track_id = ?", req.TrackID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, models.ErrTrackNotExists)
+		}
+
+		c.Logger().Error(err)
+		return err
+	}
+
+	switch req.Source {
+	case playbackPlaylist:
+		if track.PlaylistID != req.PlaylistID {
+			return echo.NewHTTPError(http.StatusNotFound, models.ErrTrackNotExists)
+		}
+
+	case playbackSmart:
+		if _, err := app.SmartPlaylistStore.GetOne("smart_playlist_id = ? AND user_id = ?", req.PlaylistID, userID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, models.ErrSmartPlaylistNotExists)
+			}
+
+			c.Logger().Error(err)
+			return err
+		}
+
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidPlaybackSource)
+	}
+
+	if _, _, err := app.authorizePlaylist(c, track.PlaylistID, RoleViewer); err != nil {
+		return err
+	}
+
+	device := req.Device
+	if len(device) > 100 {
+		device = device[:100]
+	}
+
+	if err := app.PlaybackStateStore.Save(models.PlaybackStateDBModel{
+		UserID:     userID,
+		Source:     req.Source,
+		PlaylistID: req.PlaylistID,
+		TrackID:    track.TrackID,
+		TrackIndex: req.TrackIndex,
+		PositionMs: max(req.PositionMs, 0),
+		Device:     device,
+		UpdatedAt:  time.Now(),
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleGetPlayback returns where the user left off, so any device can offer
+// to resume there.
+func (app *Application) HandleGetPlayback(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	resume, err := app.getPlaybackResume(c, userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if resume == nil {
+		return echo.NewHTTPError(http.StatusNotFound, models.ErrPlaybackStateNotExists)
+	}
+
+	return c.JSON(http.StatusOK, resume)
+}
+
+// getPlaybackResume returns nil when the user has no playback state, or when
+// its track or playlist is gone or no longer shared with them.
+func (app *Application) getPlaybackResume(c echo.Context, userID string) (*playbackResume, error) {
+	state, err := app.PlaybackStateStore.GetOne("user_id = ?", userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	track, err := app.TrackStore.GetOne("track_id = ?", state.TrackID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	playlist, err := app.PlaylistStore.GetOne("playlist_id = ?", track.PlaylistID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	role, err := app.playlistRole(c, playlist)
+	if err != nil {
+		return nil, err
+	}
+
+	if !roleAtLeast(role, RoleViewer) {
+		return nil, nil
+	}
+
+	resume := &playbackResume{
+		PlaybackStateDBModel: *state,
+		PlaylistName:         playlist.PlaylistName,
+		Title:                track.Title,
+		Artists:              track.Artists,
+	}
+
+	path := "/playlist/" + state.PlaylistID
+	if state.Source == playbackSmart {
+		smart, err := app.SmartPlaylistStore.GetOne("smart_playlist_id = ? AND user_id = ?", state.PlaylistID, userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		path = "/smart/" + state.PlaylistID
+		resume.PlaylistName = smart.Name
+	}
+
+	resume.URL = fmt.Sprintf("%s?track=%s&t=%d", path, state.TrackID, state.PositionMs/1000)
+
+	return resume, nil
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+)
+
+// Player events, reported once per track: start when playback begins,
+// then complete or skip.
+const (
+	playStart    = "start"
+	playComplete = "complete"
+	playSkip     = "skip"
+)
+
+type playRequest struct {
+	Event      string `json:"event"`
+	PositionMs int64  `json:"position_ms"`
+}
+
+type playlistPlays struct {
+	PlaylistID  string                  `json:"playlist_id"`
+	Plays       int64                   `json:"plays"`
+	Completions int64                   `json:"completions"`
+	Skips       int64                   `json:"skips"`
+	Tracks      []models.TrackPlayCount `json:"tracks"`
+}
+
+// HandleRecordPlay stores a player event of the track_id route parameter.
+// Anonymous listeners of share links have no history, their events are
+// accepted and dropped.
+func (app *Application) HandleRecordPlay(c echo.Context) error {
+	track, _, err := app.authorizeTrack(c, RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	var req playRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	if req.Event != playStart && req.Event != playComplete && req.Event != playSkip {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidPlayEvent)
+	}
+
+	userID, ok := app.currentUserID(c)
+	if !ok {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	if err := app.PlayStore.Create(models.PlayDBModel{
+		UserID:     userID,
+		TrackID:    track.TrackID,
+		PlaylistID: track.PlaylistID,
+		Event:      req.Event,
+		PositionMs: req.PositionMs,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandlePlaylistPlays returns the play counts of a playlist and its tracks
+// over all listeners.
+func (app *Application) HandlePlaylistPlays(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	plays, err := app.getPlaylistPlays(playlist.PlaylistID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, plays)
+}
+
+func (app *Application) getPlaylistPlays(playlistID string) (*playlistPlays, error) {
+	counts, err := app.PlayStore.TrackCounts(playlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	plays := &playlistPlays{
+		PlaylistID: playlistID,
+		Tracks:     counts,
+	}
+
+	for _, count := range counts {
+		plays.Plays += count.Plays
+		plays.Completions += count.Completions
+		plays.Skips += count.Skips
+	}
+
+	return plays, nil
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// usagePeriod is the accounting period streamed bytes are counted in.
+func usagePeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+func (app *Application) getUsage(userID string) (*models.Usage, error) {
+	tracks, storedBytes, err := app.TrackStore.CountAndSizeByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.Usage{
+		Tracks:           tracks,
+		StoredBytes:      storedBytes,
+		MaxTracks:        app.QuotaMaxTracks,
+		MaxStoredBytes:   app.QuotaMaxStoredBytes,
+		MaxStreamedBytes: app.QuotaMaxStreamedBytes,
+	}
+
+	streamed, err := app.UsageStore.GetOne("user_id = ? AND period = ?", userID, usagePeriod(time.Now()))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if streamed != nil {
+		usage.StreamedBytes = streamed.StreamedBytes
+	}
+
+	return usage, nil
+}
+
+// checkCreateQuota rejects a job whose tracks would take the user over the
+// track or storage limit. Track sizes are not known before the download, so
+// they are estimated from the Spotify duration.
+func (app *Application) checkCreateQuota(usage *models.Usage, tracks []*models.Track) error {
+	if usage.MaxTracks > 0 && usage.Tracks+int64(len(tracks)) > usage.MaxTracks {
+		return fmt.Errorf("%w: %d of %d tracks used, the playlist has %d", models.ErrTrackQuotaExceeded, usage.Tracks, usage.MaxTracks, len(tracks))
+	}
+
+	if usage.MaxStoredBytes > 0 {
+		var estimate int64
+		for _, track := range tracks {
+			estimate += track.DurationMs * app.QuotaBytesPerSecond / 1000
+		}
+
+		if usage.StoredBytes+estimate > usage.MaxStoredBytes {
+			return fmt.Errorf("%w: %s of %s used, the playlist needs about %s", models.ErrStorageQuotaExceeded,
+				formatBytes(usage.StoredBytes), formatBytes(usage.MaxStoredBytes), formatBytes(estimate))
+		}
+	}
+
+	return nil
+}
+
+// serveMeteredObject serves an object and counts the bytes sent against the
+// monthly streaming quota of userID.
+func (app *Application) serveMeteredObject(c echo.Context, userID, key string) error {
+	if err := app.checkStreamQuota(c, userID); err != nil {
+		return err
+	}
+
+	if err := app.serveObject(c, key); err != nil {
+		return err
+	}
+
+	app.recordStreamedBytes(c, userID)
+
+	return nil
+}
+
+func (app *Application) checkStreamQuota(c echo.Context, userID string) error {
+	if app.QuotaMaxStreamedBytes == 0 {
+		return nil
+	}
+
+	usage, err := app.getUsage(userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if usage.StreamedBytes >= usage.MaxStreamedBytes {
+		return echo.NewHTTPError(http.StatusTooManyRequests, models.ErrBandwidthQuotaExceeded)
+	}
+
+	return nil
+}
+
+// recordStreamedBytes counts the body bytes written for the request.
+func (app *Application) recordStreamedBytes(c echo.Context, userID string) {
+	if err := app.UsageStore.AddStreamedBytes(userID, usagePeriod(time.Now()), c.Response().Size); err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"log"
+	"path"
+	"strings"
+	"time"
+)
+
+// jobCheckpointPrefix is where playlist_creator keeps the checkpoints of
+// unfinished jobs, named after the job.
+const jobCheckpointPrefix = "jobs/"
+
+type ReconcileOptions struct {
+	DryRun bool
+
+	// GracePeriod protects objects of jobs that are still running: only
+	// unreferenced objects older than this are deleted.
+	GracePeriod time.Duration
+}
+
+type ReconcileReport struct {
+	// OrphanedPrefixes are playlist prefixes in storage without a playlists row.
+	OrphanedPrefixes []string
+	// OrphanedObjects are objects no playlists or tracks row refers to.
+	OrphanedObjects []string
+	// MissingObjects are keys of tracks rows whose object is gone.
+	MissingObjects []string
+	DeletedObjects []string
+}
+
+// Reconcile compares the blob store with the playlists and tracks tables,
+// reports orphans in both directions and, unless DryRun is set, deletes the
+// unreferenced objects that are older than the grace period.
+func (app *Application) Reconcile(ctx context.Context, opts ReconcileOptions) (*ReconcileReport, error) {
+	report := &ReconcileReport{}
+
+	objects, err := app.BlobStore.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	playlists, err := app.PlaylistStore.GetMany([]string{"playlist_id"}, "TRUE")
+	if err != nil {
+		return nil, err
+	}
+
+	tracks, err := app.TrackStore.GetMany([]string{"track_key", "hls_key"}, "TRUE")
+	if err != nil {
+		return nil, err
+	}
+
+	playlistIDs := make(map[string]bool)
+	for _, playlist := range playlists {
+		playlistIDs[playlist.PlaylistID] = true
+	}
+
+	trackKeys := make(map[string]bool)
+	hlsPrefixes := make(map[string]bool)
+	for _, track := range tracks {
+		trackKeys[track.TrackKey] = true
+
+		if track.HLSKey != "" {
+			hlsPrefixes[path.Dir(track.HLSKey)+"/"] = true
+		}
+	}
+
+	objectKeys := make(map[string]bool)
+	orphanedPrefixes := make(map[string]bool)
+	cutoff := time.Now().Add(-opts.GracePeriod)
+
+	for _, object := range objects {
+		objectKeys[object.Key] = true
+
+		playlistID := strings.SplitN(object.Key, "/", 2)[0]
+
+		switch {
+		case strings.HasPrefix(object.Key, jobCheckpointPrefix):
+			// finished jobs delete their checkpoint, so this one belongs to a
+			// job that failed or is still running
+		case !playlistIDs[playlistID]:
+			orphanedPrefixes[playlistID+"/"] = true
+		case trackKeys[object.Key] || hasAnyPrefix(object.Key, hlsPrefixes):
+			continue
+		}
+
+		report.OrphanedObjects = append(report.OrphanedObjects, object.Key)
+
+		if opts.DryRun || object.LastModified.After(cutoff) {
+			continue
+		}
+
+		if err := app.BlobStore.Delete(ctx, object.Key); err != nil {
+			return nil, err
+		}
+
+		report.DeletedObjects = append(report.DeletedObjects, object.Key)
+	}
+
+	for prefix := range orphanedPrefixes {
+		report.OrphanedPrefixes = append(report.OrphanedPrefixes, prefix)
+	}
+
+	for _, track := range tracks {
+		if !objectKeys[track.TrackKey] {
+			report.MissingObjects = append(report.MissingObjects, track.TrackKey)
+		}
+	}
+
+	return report, nil
+}
+
+// RunReconcileEvery reconciles storage every interval until ctx is done.
+func (app *Application) RunReconcileEvery(ctx context.Context, interval time.Duration, opts ReconcileOptions) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := app.Reconcile(ctx, opts)
+			if err != nil {
+				log.Println("ERROR: RECONCILING STORAGE: ", err)
+				continue
+			}
+
+			report.Log()
+		}
+	}
+}
+
+func (r *ReconcileReport) Log() {
+	for _, prefix := range r.OrphanedPrefixes {
+		log.Println("ORPHANED PREFIX (no playlist): ", prefix)
+	}
+
+	for _, key := range r.OrphanedObjects {
+		log.Println("ORPHANED OBJECT (no track): ", key)
+	}
+
+	for _, key := range r.MissingObjects {
+		log.Println("MISSING OBJECT (track without audio): ", key)
+	}
+
+	for _, key := range r.DeletedObjects {
+		log.Println("DELETED OBJECT: ", key)
+	}
+
+	log.Printf("RECONCILED STORAGE: %d orphaned prefixes, %d orphaned objects, %d missing objects, %d deleted objects",
+		len(r.OrphanedPrefixes), len(r.OrphanedObjects), len(r.MissingObjects), len(r.DeletedObjects))
+}
+
+func hasAnyPrefix(s string, prefixes map[string]bool) bool {
+	for prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
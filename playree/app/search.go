@@ -0,0 +1,82 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+)
+
+const searchPageSize = 50
+
+type searchPage struct {
+	Query      string
+	Results    []models.TrackSearchResult
+	NextOffset int
+}
+
+// HandleSearch renders the library search. Every result links into the
+// player at its track.
+func (app *Application) HandleSearch(c echo.Context) error {
+	page, err := app.searchLibrary(c)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Render(http.StatusOK, "search.html", page); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// HandleSearchTracks is the JSON version of HandleSearch.
+func (app *Application) HandleSearchTracks(c echo.Context) error {
+	page, err := app.searchLibrary(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"query":       page.Query,
+		"results":     page.Results,
+		"next_offset": page.NextOffset,
+	})
+}
+
+// searchLibrary runs the q query parameter against the visitor's library,
+// one page of searchPageSize results from offset on. NextOffset is 0 on the
+// last page.
+func (app *Application) searchLibrary(c echo.Context) (*searchPage, error) {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, err
+	}
+
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	page := &searchPage{
+		Query: strings.TrimSpace(c.QueryParam("q")),
+	}
+
+	// one extra result tells whether there is another page
+	page.Results, err = app.TrackStore.SearchLibrary(userID, page.Query, searchPageSize+1, offset)
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, err
+	}
+
+	if len(page.Results) > searchPageSize {
+		page.Results = page.Results[:searchPageSize]
+		page.NextOffset = offset + searchPageSize
+	}
+
+	return page, nil
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+)
+
+type sharingPage struct {
+	Playlist *models.PlaylistsDBModel
+	BaseURL  string
+	Shares   []models.PlaylistShareDBModel
+	Links    []models.PlaylistTokenDBModel
+}
+
+func (app *Application) HandleSharing(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	shares, err := app.PlaylistShareStore.GetManyOrdered("created_at", "playlist_id = ?", playlist.PlaylistID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	links, err := app.PlaylistTokenStore.GetManyOrdered("created_at DESC", "playlist_id = ? AND kind = ?", playlist.PlaylistID, playlistTokenShare)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := c.Render(http.StatusOK, "sharing.html", sharingPage{
+		Playlist: playlist,
+		BaseURL:  baseURL(c),
+		Shares:   shares,
+		Links:    links,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (app *Application) HandleSharePlaylist(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	userID := strings.TrimSpace(c.FormValue("user_id"))
+	role := c.FormValue("role")
+
+	if role != RoleViewer && role != RoleEditor {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRole)
+	}
+
+	if userID == playlist.UserID {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrCannotShareWithOwner)
+	}
+
+	exists, err := app.UserStore.IsExists("user_id = ?", userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if !exists {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrUserNotExists)
+	}
+
+	if err := app.PlaylistShareStore.Upsert(models.PlaylistShareDBModel{
+		PlaylistID: playlist.PlaylistID,
+		UserID:     userID,
+		Role:       role,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/playlist/"+playlist.PlaylistID+"/sharing")
+}
+
+func (app *Application) HandleUnsharePlaylist(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	if err := app.PlaylistShareStore.Delete("playlist_id = ? AND user_id = ?", playlist.PlaylistID, c.Param("user_id")); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/playlist/"+playlist.PlaylistID+"/sharing")
+}
+
+// HandleSharedPlaylist opens the player for an anonymous share link. The
+// token is kept in the session, where playlistRole finds it for the player's
+// API and stream requests.
+func (app *Application) HandleSharedPlaylist(c echo.Context) error {
+	token, err := app.getPlaylistToken(c, playlistTokenShare)
+	if err != nil {
+		return err
+	}
+
+	if err := setSession(c, map[string]any{"share_token": token.Token}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := c.File("./public/playlist/playlist.html"); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSmartTrackLimit = 50
+	maxSmartTrackLimit     = 500
+)
+
+// smartRuleKinds are the rules offered on the smart playlists page, with the
+// window used when none is given.
+var smartRuleKinds = []struct {
+	Kind        string
+	Label       string
+	DefaultDays int
+}{
+	{models.SmartMostPlayed, "Most played", 30},
+	{models.SmartRecentlyAdded, "Recently added", 7},
+	{models.SmartByArtist, "By artist", 0},
+	{models.SmartNeverPlayed, "Never played", 0},
+	{models.SmartLongestUnplayed, "Longest unplayed", 0},
+}
+
+type smartPlaylistView struct {
+	models.SmartPlaylistDBModel
+	Rule models.SmartRule
+}
+
+func (app *Application) HandleSmartPlaylists(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	playlists, err := app.SmartPlaylistStore.GetManyOrdered("created_at", "user_id = ?", userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	views := []smartPlaylistView{}
+	for _, playlist := range playlists {
+		view := smartPlaylistView{SmartPlaylistDBModel: playlist}
+		if err := json.Unmarshal([]byte(playlist.Rule), &view.Rule); err != nil {
+			c.Logger().Error(err)
+		}
+
+		views = append(views, view)
+	}
+
+	if err := c.Render(http.StatusOK, "smart.html", map[string]any{
+		"Playlists": views,
+		"Kinds":     smartRuleKinds,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (app *Application) HandleCreateSmartPlaylist(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	rule, err := parseSmartRule(c.FormValue("kind"), c.FormValue("days"), c.FormValue("artist"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	limit := defaultSmartTrackLimit
+	if value := c.FormValue("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxSmartTrackLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+		}
+	}
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := app.SmartPlaylistStore.Create(models.SmartPlaylistDBModel{
+		SmartPlaylistID: uuid.NewString(),
+		UserID:          userID,
+		Name:            name,
+		Rule:            string(data),
+		TrackLimit:      limit,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/smart")
+}
+
+func (app *Application) HandleDeleteSmartPlaylist(c echo.Context) error {
+	playlist, err := app.getSmartPlaylist(c)
+	if err != nil {
+		return err
+	}
+
+	if err := app.SmartPlaylistStore.Delete("smart_playlist_id = ?", playlist.SmartPlaylistID); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/smart")
+}
+
+// HandleSmartPlaylist opens the player for a smart playlist, which loads the
+// tracks from HandleSmartPlaylistTracks.
+func (app *Application) HandleSmartPlaylist(c echo.Context) error {
+	if _, err := app.getSmartPlaylist(c); err != nil {
+		return err
+	}
+
+	if err := c.File("./public/playlist/playlist.html"); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// HandleSmartPlaylistTracks materialises a smart playlist. The tracks are
+// existing ones from the user's playlists, so they stream from /stream like
+// any other track.
+func (app *Application) HandleSmartPlaylistTracks(c echo.Context) error {
+	playlist, err := app.getSmartPlaylist(c)
+	if err != nil {
+		return err
+	}
+
+	tracks, err := app.materializeSmartPlaylist(playlist)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, tracks)
+}
+
+func (app *Application) materializeSmartPlaylist(playlist *models.SmartPlaylistDBModel) ([]models.TrackDBModel, error) {
+	var rule models.SmartRule
+	if err := json.Unmarshal([]byte(playlist.Rule), &rule); err != nil {
+		return nil, err
+	}
+
+	return app.SmartPlaylistStore.Tracks(playlist.UserID, rule, playlist.TrackLimit)
+}
+
+// getSmartPlaylist loads the smart playlist of the smart_playlist_id route
+// parameter. Smart playlists are private to the user who defined them.
+func (app *Application) getSmartPlaylist(c echo.Context) (*models.SmartPlaylistDBModel, error) {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, err
+	}
+
+	playlist, err := app.SmartPlaylistStore.GetOne("smart_playlist_id = ? AND user_id = ?", c.Param("smart_playlist_id"), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, models.ErrSmartPlaylistNotExists)
+		}
+
+		c.Logger().Error(err)
+		return nil, err
+	}
+
+	return playlist, nil
+}
+
+func parseSmartRule(kind, days, artist string) (models.SmartRule, error) {
+	rule := models.SmartRule{Kind: kind}
+
+	found := false
+	for _, k := range smartRuleKinds {
+		if k.Kind == kind {
+			found = true
+			rule.Days = k.DefaultDays
+		}
+	}
+
+	if !found {
+		return rule, models.ErrInvalidSmartRule
+	}
+
+	switch kind {
+	case models.SmartMostPlayed, models.SmartRecentlyAdded:
+		if days != "" {
+			n, err := strconv.Atoi(days)
+			if err != nil || n <= 0 {
+				return rule, models.ErrInvalidSmartRule
+			}
+
+			rule.Days = n
+		}
+
+	case models.SmartByArtist:
+		rule.Artist = strings.TrimSpace(artist)
+		if rule.Artist == "" {
+			return rule, models.ErrInvalidSmartRule
+		}
+	}
+
+	return rule, nil
+}
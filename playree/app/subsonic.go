@@ -0,0 +1,455 @@
+package app
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	subsonicAPIVersion = "1.16.1"
+	subsonicServerType = "playree"
+)
+
+// Subsonic error codes, see http://www.subsonic.org/pages/api.jsp
+const (
+	subsonicErrGeneric         = 0
+	subsonicErrMissingParam    = 10
+	subsonicErrWrongCredential = 40
+	subsonicErrNotFound        = 70
+)
+
+type subsonicResponse struct {
+	XMLName       xml.Name `xml:"subsonic-response" json:"-"`
+	XMLNS         string   `xml:"xmlns,attr" json:"-"`
+	Status        string   `xml:"status,attr" json:"status"`
+	Version       string   `xml:"version,attr" json:"version"`
+	Type          string   `xml:"type,attr" json:"type"`
+	ServerVersion string   `xml:"serverVersion,attr" json:"serverVersion"`
+	OpenSubsonic  bool     `xml:"openSubsonic,attr" json:"openSubsonic"`
+
+	Error         *subsonicError         `xml:"error,omitempty" json:"error,omitempty"`
+	Playlists     *subsonicPlaylists     `xml:"playlists,omitempty" json:"playlists,omitempty"`
+	Playlist      *subsonicPlaylist      `xml:"playlist,omitempty" json:"playlist,omitempty"`
+	SearchResult3 *subsonicSearchResult3 `xml:"searchResult3,omitempty" json:"searchResult3,omitempty"`
+}
+
+type subsonicError struct {
+	Code    int    `xml:"code,attr" json:"code"`
+	Message string `xml:"message,attr" json:"message"`
+}
+
+type subsonicPlaylists struct {
+	Playlist []subsonicPlaylist `xml:"playlist" json:"playlist"`
+}
+
+type subsonicPlaylist struct {
+	ID        string         `xml:"id,attr" json:"id"`
+	Name      string         `xml:"name,attr" json:"name"`
+	Owner     string         `xml:"owner,attr" json:"owner"`
+	Public    bool           `xml:"public,attr" json:"public"`
+	SongCount int            `xml:"songCount,attr" json:"songCount"`
+	Duration  int64          `xml:"duration,attr" json:"duration"`
+	CoverArt  string         `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	Entry     []subsonicSong `xml:"entry" json:"entry,omitempty"`
+}
+
+type subsonicSong struct {
+	ID          string `xml:"id,attr" json:"id"`
+	Parent      string `xml:"parent,attr" json:"parent"`
+	IsDir       bool   `xml:"isDir,attr" json:"isDir"`
+	Title       string `xml:"title,attr" json:"title"`
+	Album       string `xml:"album,attr,omitempty" json:"album,omitempty"`
+	Artist      string `xml:"artist,attr,omitempty" json:"artist,omitempty"`
+	Track       int    `xml:"track,attr" json:"track"`
+	Duration    int64  `xml:"duration,attr" json:"duration"`
+	Size        int64  `xml:"size,attr,omitempty" json:"size,omitempty"`
+	Suffix      string `xml:"suffix,attr" json:"suffix"`
+	ContentType string `xml:"contentType,attr" json:"contentType"`
+	CoverArt    string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	Type        string `xml:"type,attr" json:"type"`
+	Created     string `xml:"created,attr,omitempty" json:"created,omitempty"`
+}
+
+// subsonicSearchResult3 has no artists or albums: playree only knows songs
+// grouped in playlists.
+type subsonicSearchResult3 struct {
+	Artist []struct{}     `xml:"-" json:"artist"`
+	Album  []struct{}     `xml:"-" json:"album"`
+	Song   []subsonicSong `xml:"song" json:"song"`
+}
+
+func (app *Application) SubsonicRoutes(g *echo.Group) {
+	handlers := map[string]echo.HandlerFunc{
+		"ping":         app.HandleSubsonicPing,
+		"getPlaylists": app.HandleSubsonicGetPlaylists,
+		"getPlaylist":  app.HandleSubsonicGetPlaylist,
+		"stream":       app.HandleSubsonicStream,
+		"download":     app.HandleSubsonicStream,
+		"getCoverArt":  app.HandleSubsonicGetCoverArt,
+		"search3":      app.HandleSubsonicSearch3,
+	}
+
+	// clients call both /rest/ping and /rest/ping.view, with GET or POST
+	for name, handler := range handlers {
+		g.Match([]string{http.MethodGet, http.MethodPost}, "/"+name, handler)
+		g.Match([]string{http.MethodGet, http.MethodPost}, "/"+name+".view", handler)
+	}
+}
+
+// SubsonicAuth authenticates Subsonic requests with an app password, sent
+// either as p (plain or "enc:" hex) or as the salted md5 token t and s.
+func (app *Application) SubsonicAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID := c.FormValue("u")
+		if userID == "" {
+			return subsonicFail(c, subsonicErrMissingParam, "required parameter is missing: u")
+		}
+
+		password, token, salt := c.FormValue("p"), c.FormValue("t"), c.FormValue("s")
+		if password == "" && (token == "" || salt == "") {
+			return subsonicFail(c, subsonicErrMissingParam, "required parameter is missing: p or t and s")
+		}
+
+		if strings.HasPrefix(password, "enc:") {
+			decoded, err := hex.DecodeString(strings.TrimPrefix(password, "enc:"))
+			if err != nil {
+				return subsonicFail(c, subsonicErrWrongCredential, "wrong username or password")
+			}
+
+			password = string(decoded)
+		}
+
+		passwords, err := app.AppPasswordStore.GetManyOrdered("created_at", "user_id = ?", userID)
+		if err != nil {
+			c.Logger().Error(err)
+			return subsonicFail(c, subsonicErrGeneric, err.Error())
+		}
+
+		for _, appPassword := range passwords {
+			secret, err := decryptSecret(app.AppPasswordKey, appPassword.Secret)
+			if err != nil {
+				c.Logger().Error(err)
+				continue
+			}
+
+			if !subsonicPasswordMatches(secret, password, token, salt) {
+				continue
+			}
+
+			if err := app.AppPasswordStore.Update(map[string]any{"last_used_at": time.Now()}, "id = ?", appPassword.ID); err != nil {
+				c.Logger().Error(err)
+			}
+
+			c.Set("subsonic_user_id", userID)
+			return next(c)
+		}
+
+		return subsonicFail(c, subsonicErrWrongCredential, "wrong username or password")
+	}
+}
+
+func subsonicPasswordMatches(secret, password, token, salt string) bool {
+	if password != "" {
+		return subtle.ConstantTimeCompare([]byte(secret), []byte(password)) == 1
+	}
+
+	sum := md5.Sum([]byte(secret + salt))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(token))) == 1
+}
+
+func (app *Application) HandleSubsonicPing(c echo.Context) error {
+	return subsonicOK(c, newSubsonicResponse())
+}
+
+func (app *Application) HandleSubsonicGetPlaylists(c echo.Context) error {
+	userID := c.Get("subsonic_user_id").(string)
+
+	playlists, err := app.userPlaylists(userID, -1, 0)
+	if err != nil {
+		c.Logger().Error(err)
+		return subsonicFail(c, subsonicErrGeneric, err.Error())
+	}
+
+	playlistIDs := []string{}
+	for _, playlist := range playlists {
+		playlistIDs = append(playlistIDs, playlist.PlaylistID)
+	}
+
+	tracks, err := app.TrackStore.GetMany([]string{"playlist_id", "duration_ms"}, "playlist_id IN ?", playlistIDs)
+	if err != nil {
+		c.Logger().Error(err)
+		return subsonicFail(c, subsonicErrGeneric, err.Error())
+	}
+
+	counts, durations := map[string]int{}, map[string]int64{}
+	for _, track := range tracks {
+		counts[track.PlaylistID]++
+		durations[track.PlaylistID] += track.DurationMs / 1000
+	}
+
+	resp := newSubsonicResponse()
+	resp.Playlists = &subsonicPlaylists{Playlist: []subsonicPlaylist{}}
+
+	for _, playlist := range playlists {
+		entry := newSubsonicPlaylist(playlist.PlaylistsDBModel)
+		entry.SongCount = counts[playlist.PlaylistID]
+		entry.Duration = durations[playlist.PlaylistID]
+
+		resp.Playlists.Playlist = append(resp.Playlists.Playlist, entry)
+	}
+
+	return subsonicOK(c, resp)
+}
+
+func (app *Application) HandleSubsonicGetPlaylist(c echo.Context) error {
+	userID := c.Get("subsonic_user_id").(string)
+
+	id := c.FormValue("id")
+	if id == "" {
+		return subsonicFail(c, subsonicErrMissingParam, "required parameter is missing: id")
+	}
+
+	playlist, err := app.getSubsonicPlaylist(userID, id)
+	if err != nil {
+		if errors.Is(err, models.ErrPlaylistNotExists) {
+			return subsonicFail(c, subsonicErrNotFound, err.Error())
+		}
+
+		c.Logger().Error(err)
+		return subsonicFail(c, subsonicErrGeneric, err.Error())
+	}
+
+	tracks, err := app.TrackStore.GetManyOrdered([]string{"*"}, "position", "playlist_id = ?", playlist.PlaylistID)
+	if err != nil {
+		c.Logger().Error(err)
+		return subsonicFail(c, subsonicErrGeneric, err.Error())
+	}
+
+	entry := newSubsonicPlaylist(*playlist)
+	entry.Entry = []subsonicSong{}
+
+	for _, track := range tracks {
+		if track.AudioSizeBytes == 0 {
+			track.AudioSizeBytes = app.recordAudioSize(c, track)
+		}
+
+		entry.SongCount++
+		entry.Duration += track.DurationMs / 1000
+		entry.Entry = append(entry.Entry, newSubsonicSong(track))
+	}
+
+	resp := newSubsonicResponse()
+	resp.Playlist = &entry
+
+	return subsonicOK(c, resp)
+}
+
+func (app *Application) HandleSubsonicStream(c echo.Context) error {
+	userID := c.Get("subsonic_user_id").(string)
+
+	track, err := app.getSubsonicTrack(userID, c.FormValue("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return subsonicFail(c, subsonicErrNotFound, models.ErrTrackNotExists.Error())
+		}
+
+		c.Logger().Error(err)
+		return subsonicFail(c, subsonicErrGeneric, err.Error())
+	}
+
+	return app.serveMeteredObject(c, userID, track.TrackKey)
+}
+
+// HandleSubsonicGetCoverArt proxies the spotify artwork of a playlist. Songs
+// use the artwork of their playlist.
+func (app *Application) HandleSubsonicGetCoverArt(c echo.Context) error {
+	userID := c.Get("subsonic_user_id").(string)
+
+	playlist, err := app.getSubsonicPlaylist(userID, c.FormValue("id"))
+	if err != nil {
+		if errors.Is(err, models.ErrPlaylistNotExists) {
+			return subsonicFail(c, subsonicErrNotFound, "cover art not found")
+		}
+
+		c.Logger().Error(err)
+		return subsonicFail(c, subsonicErrGeneric, err.Error())
+	}
+
+	if playlist.ArtworkURL == "" {
+		return subsonicFail(c, subsonicErrNotFound, "cover art not found")
+	}
+
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodGet, playlist.ArtworkURL, nil)
+	if err != nil {
+		c.Logger().Error(err)
+		return subsonicFail(c, subsonicErrGeneric, err.Error())
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.Logger().Error(err)
+		return subsonicFail(c, subsonicErrGeneric, err.Error())
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return subsonicFail(c, subsonicErrNotFound, "cover art not found")
+	}
+
+	c.Response().Header().Set("Cache-Control", "private, max-age=86400")
+	c.Response().Header().Set(echo.HeaderContentType, resp.Header.Get(echo.HeaderContentType))
+	c.Response().WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(c.Response(), resp.Body)
+	return err
+}
+
+func (app *Application) HandleSubsonicSearch3(c echo.Context) error {
+	userID := c.Get("subsonic_user_id").(string)
+
+	// some clients send "" to list everything
+	query := strings.Trim(c.FormValue("query"), `"`)
+
+	count := formInt(c, "songCount", 20)
+	if count > 500 {
+		count = 500
+	}
+
+	tracks, err := app.TrackStore.SearchByUser(userID, query, count, formInt(c, "songOffset", 0))
+	if err != nil {
+		c.Logger().Error(err)
+		return subsonicFail(c, subsonicErrGeneric, err.Error())
+	}
+
+	resp := newSubsonicResponse()
+	resp.SearchResult3 = &subsonicSearchResult3{
+		Artist: []struct{}{},
+		Album:  []struct{}{},
+		Song:   []subsonicSong{},
+	}
+
+	for _, track := range tracks {
+		if track.AudioSizeBytes == 0 {
+			track.AudioSizeBytes = app.recordAudioSize(c, track)
+		}
+
+		resp.SearchResult3.Song = append(resp.SearchResult3.Song, newSubsonicSong(track))
+	}
+
+	return subsonicOK(c, resp)
+}
+
+// getSubsonicPlaylist loads a playlist userID owns or that is shared with
+// them. Playlists they cannot see are reported as not existing.
+func (app *Application) getSubsonicPlaylist(userID, playlistID string) (*models.PlaylistsDBModel, error) {
+	playlist, _, err := app.authorizeUserPlaylist(userID, playlistID, RoleViewer)
+	if errors.Is(err, models.ErrPlaylistAccessDenied) {
+		return nil, models.ErrPlaylistNotExists
+	}
+
+	return playlist, err
+}
+
+func (app *Application) getSubsonicTrack(userID, trackID string) (*models.TrackDBModel, error) {
+	return app.TrackStore.GetOne(
+		"track_id = ? AND playlist_id IN (SELECT playlist_id FROM playlists WHERE user_id = ? OR playlist_id IN (SELECT playlist_id FROM playlist_shares WHERE user_id = ?))",
+		trackID, userID, userID,
+	)
+}
+
+func newSubsonicResponse() *subsonicResponse {
+	return &subsonicResponse{
+		XMLNS:         "http://subsonic.org/restapi",
+		Status:        "ok",
+		Version:       subsonicAPIVersion,
+		Type:          subsonicServerType,
+		ServerVersion: subsonicAPIVersion,
+		OpenSubsonic:  true,
+	}
+}
+
+func newSubsonicPlaylist(playlist models.PlaylistsDBModel) subsonicPlaylist {
+	entry := subsonicPlaylist{
+		ID:    playlist.PlaylistID,
+		Name:  playlist.PlaylistName,
+		Owner: playlist.UserID,
+	}
+
+	if playlist.ArtworkURL != "" {
+		entry.CoverArt = playlist.PlaylistID
+	}
+
+	return entry
+}
+
+func newSubsonicSong(track models.TrackDBModel) subsonicSong {
+	suffix := strings.TrimPrefix(path.Ext(track.TrackKey), ".")
+
+	return subsonicSong{
+		ID:          track.TrackID,
+		Parent:      track.PlaylistID,
+		Title:       track.Title,
+		Album:       track.Album,
+		Artist:      track.Artists,
+		Track:       track.Position + 1,
+		Duration:    track.DurationMs / 1000,
+		Size:        track.AudioSizeBytes,
+		Suffix:      suffix,
+		ContentType: "audio/mpeg",
+		CoverArt:    track.PlaylistID,
+		Type:        "music",
+		Created:     track.InsertedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// subsonicOK writes resp as XML, or as JSON when the client asks with f=json.
+// Subsonic reports errors inside the body, so the status is always 200.
+func subsonicOK(c echo.Context, resp *subsonicResponse) error {
+	if c.FormValue("f") == "json" {
+		data, err := json.Marshal(map[string]any{"subsonic-response": resp})
+		if err != nil {
+			return err
+		}
+
+		return c.JSONBlob(http.StatusOK, data)
+	}
+
+	data, err := xml.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	return c.Blob(http.StatusOK, echo.MIMETextXMLCharsetUTF8, append([]byte(xml.Header), data...))
+}
+
+func subsonicFail(c echo.Context, code int, message string) error {
+	resp := newSubsonicResponse()
+	resp.Status = "failed"
+	resp.Error = &subsonicError{Code: code, Message: message}
+
+	return subsonicOK(c, resp)
+}
+
+func formInt(c echo.Context, name string, def int) int {
+	n, err := strconv.Atoi(c.FormValue(name))
+	if err != nil || n < 0 {
+		return def
+	}
+
+	return n
+}
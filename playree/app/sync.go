@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// HandleSyncPlaylist starts a sync of the playlist with its Spotify playlist
+// and goes back to the manage page.
+func (app *Application) HandleSyncPlaylist(c echo.Context) error {
+	playlist := getPlaylist(c)
+
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if _, err := app.startSyncJob(c.Request().Context(), playlist, userID); err != nil {
+		return app.httpError(c, err)
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/playlist/"+playlist.PlaylistID+"/manage")
+}
+
+func (app *Application) APISyncPlaylist(c echo.Context) error {
+	job, err := app.startSyncJob(c.Request().Context(), getPlaylist(c), c.Get("user_id").(string))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// startSyncJob starts a job that appends the tracks of the playlist's Spotify
+// playlist it does not have yet, looked up with the Spotify access of userID.
+// Tracks removed on Spotify are kept. When there is nothing to add the job is
+// done right away.
+func (app *Application) startSyncJob(ctx context.Context, playlist *models.PlaylistsDBModel, userID string) (*models.JobDBModel, error) {
+	if playlist.SpotifyPlaylistID == "" {
+		return nil, models.ErrPlaylistNotSyncable
+	}
+
+	client, err := app.spotifyClient(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tracksData, _, _, err := getNameAndTracksFromPlaylist(client, playlist.SpotifyPlaylistID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := app.TrackStore.GetMany([]string{"spotify_track_id"}, "playlist_id = ?", playlist.PlaylistID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, track := range existing {
+		seen[track.SpotifyTrackID] = true
+	}
+
+	missing := []*models.Track{}
+	for _, track := range tracksData {
+		// local files of a Spotify playlist have no id and cannot be downloaded
+		if track.SpotifyTrackID == "" || seen[track.SpotifyTrackID] {
+			continue
+		}
+
+		seen[track.SpotifyTrackID] = true
+		missing = append(missing, track)
+	}
+
+	job := &models.JobDBModel{
+		JobID:      uuid.NewString(),
+		PlaylistID: playlist.PlaylistID,
+		UserID:     userID,
+		Kind:       jobSync,
+
+		SpotifyPlaylistID: playlist.SpotifyPlaylistID,
+	}
+
+	if len(missing) == 0 {
+		now := time.Now()
+
+		job.Status = jobDone
+		job.Tracks = "[]"
+		job.CreatedAt = now
+		job.FinishedAt = &now
+
+		if err := app.JobStore.Create(*job); err != nil {
+			return nil, err
+		}
+
+		app.emitJobEvent(EventJobStarted, job, playlist)
+		app.emitJobEvent(EventJobFinished, job, playlist)
+		app.emitJobEvent(EventPlaylistSynced, job, playlist)
+
+		return job, nil
+	}
+
+	usage, err := app.getUsage(playlist.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.checkCreateQuota(usage, missing); err != nil {
+		return nil, err
+	}
+
+	if err := app.publishJob(ctx, job, playlist, missing); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
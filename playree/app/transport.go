@@ -2,7 +2,6 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
@@ -12,11 +11,9 @@ import (
 	"time"
 
 	"github.com/NikhilSharmaWe/playree/playree/models"
-	"github.com/NikhilSharmaWe/rabbitmq"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/zmb3/spotify/v2"
 )
 
@@ -27,22 +24,86 @@ func (app *Application) Router() *echo.Echo {
 	e.Use(app.CreateSessionMiddleware)
 	e.Static("/assets", "./public")
 	e.Renderer = &Template{
-		templates: template.Must(template.ParseGlob("./public/*/*.html")),
+		templates: template.Must(template.New("").Funcs(template.FuncMap{
+			"bytes": formatBytes,
+		}).ParseGlob("./public/*/*.html")),
 	}
 
 	e.GET("/", ServeFile("./public/login/login.html"), app.IfAlreadyLogined)
 	e.GET("/signup", ServeFile("./public/signup/signup.html"), app.IfAlreadyLogined)
-	e.GET("/home", ServeFile("./public/home/home.html"), app.IfNotLogined)
+	e.GET("/home", app.HandleHome, app.IfNotLogined)
 	e.GET("/create_playlist", ServeFile("./public/create_playlist/create_playlist.html"), app.IfNotLogined)
 	e.GET("/my-playlists", app.HandlePlaylists, app.IfNotLogined)
+	e.GET("/search", app.HandleSearch, app.IfNotLogined)
+	e.GET("/playback", app.HandleGetPlayback, app.IfNotLogined)
+	e.POST("/playback", app.HandleSavePlayback, app.IfNotLogined)
+	e.GET("/smart", app.HandleSmartPlaylists, app.IfNotLogined)
+	e.POST("/smart", app.HandleCreateSmartPlaylist, app.IfNotLogined)
+	e.GET("/smart/:smart_playlist_id", app.HandleSmartPlaylist, app.IfNotLogined)
+	e.GET("/smart/:smart_playlist_id/tracks", app.HandleSmartPlaylistTracks, app.IfNotLogined)
+	e.POST("/smart/:smart_playlist_id/delete", app.HandleDeleteSmartPlaylist, app.IfNotLogined)
+	e.GET("/search/tracks", app.HandleSearchTracks, app.IfNotLogined)
 
 	e.GET("/spotify-auth", app.HandleSpotifyAuth)
 	e.GET(app.SpotifyRedirectPath, app.HandleSpotifyRedirect)
 	e.GET("/logout", app.HandleLogout, app.IfNotLogined)
-	e.GET("/playlist/:playlist_id", app.HandlePlaylist, app.IfNotLogined, app.UpdateTrackURIsIfAboutToExpire)
+	e.GET("/playlist/:playlist_id", app.HandlePlaylist, app.IfNotLogined, app.RequirePlaylistRole(RoleViewer))
+	e.GET("/playlist/:playlist_id/plays", app.HandlePlaylistPlays, app.IfNotLogined, app.RequirePlaylistRole(RoleViewer))
+	e.GET("/playlist/:playlist_id/download", app.HandleDownloadPlaylist, app.IfNotLogined, app.RequirePlaylistRole(RoleViewer))
+	e.GET("/playlist/:playlist_id/manage", app.HandleManagePlaylist, app.IfNotLogined, app.RequirePlaylistRole(RoleEditor))
+	e.GET("/playlist/:playlist_id/add", app.HandleAddTracks, app.IfNotLogined, app.RequirePlaylistRole(RoleEditor), app.UpdateSpotifyTokenIfExpired)
+	e.POST("/playlist/:playlist_id/tracks", app.HandleCreateAddJob, app.IfNotLogined, app.RequirePlaylistRole(RoleEditor), app.UpdateSpotifyTokenIfExpired)
+	e.POST("/playlist/:playlist_id/rename", app.HandleRenamePlaylist, app.IfNotLogined, app.RequirePlaylistRole(RoleEditor))
+	e.POST("/playlist/:playlist_id/sync", app.HandleSyncPlaylist, app.IfNotLogined, app.RequirePlaylistRole(RoleEditor))
+	e.POST("/playlist/:playlist_id/reorder", app.HandleReorderTracks, app.IfNotLogined, app.RequirePlaylistRole(RoleEditor))
+	e.POST("/playlist/:playlist_id/tracks/:track_id/delete", app.HandleRemoveTrack, app.IfNotLogined, app.RequirePlaylistRole(RoleEditor))
+	e.POST("/playlist/:playlist_id/delete", app.HandleDeletePlaylist, app.IfNotLogined, app.RequirePlaylistRole(RoleOwner))
+	e.GET("/playlist/:playlist_id/exports", app.HandleExports, app.IfNotLogined, app.RequirePlaylistRole(RoleOwner))
+	e.POST("/playlist/:playlist_id/exports", app.HandleCreatePlaylistToken, app.IfNotLogined, app.RequirePlaylistRole(RoleOwner))
+	e.POST("/playlist/:playlist_id/exports/:token/revoke", app.HandleRevokePlaylistToken, app.IfNotLogined, app.RequirePlaylistRole(RoleOwner))
+	e.GET("/playlist/:playlist_id/sharing", app.HandleSharing, app.IfNotLogined, app.RequirePlaylistRole(RoleOwner))
+	e.POST("/playlist/:playlist_id/sharing", app.HandleSharePlaylist, app.IfNotLogined, app.RequirePlaylistRole(RoleOwner))
+	e.POST("/playlist/:playlist_id/sharing/:user_id/delete", app.HandleUnsharePlaylist, app.IfNotLogined, app.RequirePlaylistRole(RoleOwner))
+
+	// anonymous share links, the player authorizes them through the session,
+	// streams also accept personal access tokens
+	e.GET("/s/:token", app.HandleSharedPlaylist)
+	e.GET("/stream/:track_id", app.HandleStreamTrack, app.BearerAuth)
+	e.GET("/stream/:track_id/hls/*", app.HandleStreamTrackHLS, app.BearerAuth)
+	e.POST("/tracks/:track_id/plays", app.HandleRecordPlay)
+
+	// export urls are opened by external players, the token replaces the session
+	e.GET("/export/:token/playlist.m3u8", app.HandleExportM3U8)
+	e.GET("/export/:token/playlist.xspf", app.HandleExportXSPF)
+	e.GET("/export/:token/tracks/:track_id", app.HandleExportStreamTrack)
+	e.GET("/feed/:token", app.HandleFeed)
+
+	e.GET("/app-passwords", app.HandleAppPasswords, app.IfNotLogined)
+	e.POST("/app-passwords", app.HandleCreateAppPassword, app.IfNotLogined)
+	e.POST("/app-passwords/:id/delete", app.HandleDeleteAppPassword, app.IfNotLogined)
+
+	e.GET("/access-tokens", app.HandleAccessTokens, app.IfNotLogined)
+	e.POST("/access-tokens", app.HandleCreateAccessToken, app.IfNotLogined)
+	e.POST("/access-tokens/:id/revoke", app.HandleRevokeAccessToken, app.IfNotLogined)
+
+	e.GET("/webhooks", app.HandleWebhooks, app.IfNotLogined)
+	e.POST("/webhooks", app.HandleCreateWebhook, app.IfNotLogined)
+	e.GET("/webhooks/:webhook_id", app.HandleWebhook, app.IfNotLogined)
+	e.POST("/webhooks/:webhook_id/ping", app.HandlePingWebhook, app.IfNotLogined)
+	e.POST("/webhooks/:webhook_id/delete", app.HandleDeleteWebhook, app.IfNotLogined)
+
+	// the JSON API, documented in API.md
+	app.APIRoutes(e.Group("/api/v1"))
+
+	// Subsonic clients authenticate every request with an app password
+	app.SubsonicRoutes(e.Group("/rest", app.SubsonicAuth))
+
+	// the local blob store verifies its own signed urls
+	if handler, ok := app.BlobStore.(http.Handler); ok {
+		e.GET("/blobs/*", echo.WrapHandler(http.StripPrefix("/blobs", handler)))
+	}
 
 	e.GET("/start-processing", app.HandleCreatePlaylistProcess, app.IfNotLogined, app.UpdateSpotifyTokenIfExpired)
-	e.GET("/send-playlist-data", app.HandlePlaylistData, app.IfNotLogined)
 
 	e.POST("/create_playlist", app.HandleCreatePlaylist, app.IfNotLogined)
 
@@ -55,6 +116,52 @@ func ServeFile(path string) echo.HandlerFunc {
 	}
 }
 
+func (app *Application) HandleHome(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	usage, err := app.getUsage(userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	recentlyPlayed, err := app.PlayStore.RecentlyPlayed(userID, 10)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	mostPlayed, err := app.PlayStore.MostPlayed(userID, time.Time{}, 10)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	resume, err := app.getPlaybackResume(c, userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	data := map[string]any{
+		"Resume":         resume,
+		"Usage":          usage,
+		"RecentlyPlayed": recentlyPlayed,
+		"MostPlayed":     mostPlayed,
+	}
+
+	if err := c.Render(http.StatusOK, "home.html", data); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
 func (app *Application) HandleSpotifyAuth(c echo.Context) error {
 	action := c.QueryParam("action")
 	if action != "signup" && action != "login" {
@@ -201,13 +308,11 @@ func (app *Application) HandleCreatePlaylist(c echo.Context) error {
 	return nil
 }
 
+// HandleCreatePlaylistProcess starts the import of the Spotify playlist kept
+// in the session and reports on the websocket until the job is finished.
+// The job itself does not depend on the websocket, the playlist also shows
+// up when the browser goes away.
 func (app *Application) HandleCreatePlaylistProcess(c echo.Context) error {
-	var (
-		errCh           = make(chan error)
-		playlistCreated = make(chan string)
-		resp            models.RabbitMQCreatePlaylistResponse
-	)
-
 	conn, err := app.Upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		c.Logger().Error(err)
@@ -232,115 +337,50 @@ func (app *Application) HandleCreatePlaylistProcess(c echo.Context) error {
 		return err
 	}
 
-	token, err := app.TokenStore.Get(context.Background(), userID)
+	job, err := app.startCreateJob(context.Background(), userID, playlistID)
 	if err != nil {
-		c.Logger().Error(err)
-		sendFailStatusToFrontend(conn)
-		return err
-	}
-
-	spotifyClient := spotify.New(app.Authenticator.Client(context.Background(), token))
-
-	defer func() {
-		if err := app.updateTokenFromClientIfNeeded(token, spotifyClient, userID); err != nil {
-			c.Logger().Error(err)
+		if status := errorStatus(err); status != http.StatusInternalServerError {
+			sendMessageToFrontend(conn, "Error: "+err.Error())
+			return echo.NewHTTPError(status, err)
 		}
-	}()
 
-	tracksData, playlistName, err := getNameAndTracksFromPlaylist(spotifyClient, playlistID)
-	if err != nil {
 		c.Logger().Error(err)
 		sendFailStatusToFrontend(conn)
 		return err
 	}
 
-	playreePlaylistID := uuid.NewString()
-
-	createPlaylistReq := models.CreatePlaylistRequest{
-		PlayreePlaylistID: playreePlaylistID,
-		Tracks:            tracksData,
-	}
-
-	app.CreatePlaylistResponseChannel[playreePlaylistID] = make(chan models.RabbitMQCreatePlaylistResponse)
-	defer delete(app.CreatePlaylistResponseChannel, playreePlaylistID)
+	sendMessageToFrontend(conn, "creating playlist")
 
-	go func() {
-		resp = <-app.CreatePlaylistResponseChannel[playreePlaylistID]
-		if !resp.Success {
-			errCh <- errors.New(resp.Error)
-		} else {
-			playlistCreated <- "playlist-created"
-		}
-	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
 
-	rabbitMQClient, err := rabbitmq.NewRabbitMQClient(app.PublishingConn)
+	job, err = app.waitForJob(ctx, job.JobID)
 	if err != nil {
-		c.Logger().Error(err)
-		sendFailStatusToFrontend(conn)
-		return err
-	}
-
-	defer rabbitMQClient.Close()
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.Logger().Error(models.ErrCreatePlaylistServiceTimeout)
+			sendMessageToFrontend(conn, "still creating, the playlist shows up in My Playlists once it is done")
+			return nil
+		}
 
-	body, err := json.Marshal(createPlaylistReq)
-	if err != nil {
 		c.Logger().Error(err)
 		sendFailStatusToFrontend(conn)
 		return err
 	}
 
-	if err := rabbitMQClient.Send(context.Background(), "create-playlist", "create-playlist-request", amqp.Publishing{
-		ContentType:  "application/json",
-		Body:         body,
-		ReplyTo:      "create-playlist-response-" + app.RabbitMQInstanceID,
-		DeliveryMode: amqp.Persistent,
-	}); err != nil {
-		c.Logger().Error(err)
+	if job.Status == jobFailed {
+		c.Logger().Error(job.Error)
 		sendFailStatusToFrontend(conn)
-		return err
+		return nil
 	}
 
-	sendMessageToFrontend(conn, "creating playlist")
-
-	ticker := time.NewTicker(5 * time.Minute)
-
-	select {
-	case <-playlistCreated:
-		playlist := models.PlaylistsDBModel{
-			PlaylistID:   playreePlaylistID,
-			PlaylistName: playlistName,
-			UserID:       userID,
-		}
-
-		if err := app.handleAfterPlaylistCreated(&playlist); err != nil {
-			c.Logger().Error(err)
-			sendFailStatusToFrontend(conn)
-			return err
-		}
-
-		sendMessageToFrontend(conn, "playlist created")
-		sendMessageToFrontend(conn, fmt.Sprintf("PLAYLIST URL:http://%s/playlist/%s", os.Getenv("ADDR"), playreePlaylistID))
+	sendMessageToFrontend(conn, "playlist created")
+	sendMessageToFrontend(conn, fmt.Sprintf("PLAYLIST URL:http://%s/playlist/%s", os.Getenv("ADDR"), job.PlaylistID))
 
-		return nil
-
-	case err := <-errCh:
-		c.Logger().Error(err)
-		return err
-	case <-ticker.C:
-		c.Logger().Error(models.ErrCreatePlaylistServiceTimeout)
-		sendMessageToFrontend(conn, "timeout error occurred")
-		return echo.NewHTTPError(http.StatusRequestTimeout, models.ErrCreatePlaylistServiceTimeout)
-	}
+	return nil
 }
 
+// HandlePlaylist serves the player, which loads the tracks from the API.
 func (app *Application) HandlePlaylist(c echo.Context) error {
-	playlistID := c.Param("playlist_id")
-
-	if err := setSession(c, map[string]any{"playlist_id": playlistID}); err != nil {
-		c.Logger().Error(err)
-		return err
-	}
-
 	if err := c.File("./public/playlist/playlist.html"); err != nil {
 		c.Logger().Error(err)
 		return err
@@ -349,39 +389,32 @@ func (app *Application) HandlePlaylist(c echo.Context) error {
 	return nil
 }
 
-func (app *Application) HandlePlaylistData(c echo.Context) error {
-	conn, err := app.Upgrader.Upgrade(c.Response(), c.Request(), nil)
+func (app *Application) HandleStreamTrack(c echo.Context) error {
+	track, playlist, err := app.authorizeTrack(c, RoleViewer)
 	if err != nil {
-		c.Logger().Error(err)
 		return err
 	}
 
-	defer conn.Close()
+	return app.serveMeteredObject(c, app.meteredUserID(c, playlist), track.TrackKey)
+}
 
-	playlistID, err := getContext(c, "playlist_id")
+func (app *Application) HandleStreamTrackHLS(c echo.Context) error {
+	track, playlist, err := app.authorizeTrack(c, RoleViewer)
 	if err != nil {
-		c.Logger().Error(err)
 		return err
 	}
 
-	tracks, err := app.TrackStore.GetMany([]string{"track_key", "track_uri"}, "playlist_id = ?", playlistID)
-	if err != nil {
-		c.Logger().Error(err)
-		return err
+	if track.HLSKey == "" {
+		return echo.NewHTTPError(http.StatusNotFound, models.ErrHLSNotExists)
 	}
 
-	data, err := json.Marshal(tracks)
-	if err != nil {
-		c.Logger().Error(err)
-		return err
+	// only objects below the track's HLS prefix may be served from here
+	name := path.Clean("/" + c.Param("*"))
+	if name == "/" {
+		return echo.NewHTTPError(http.StatusNotFound, models.ErrHLSNotExists)
 	}
 
-	if err := conn.WriteMessage(1, data); err != nil {
-		c.Logger().Error(err)
-		return err
-	}
-
-	return nil
+	return app.serveMeteredObject(c, app.meteredUserID(c, playlist), path.Dir(track.HLSKey)+name)
 }
 
 func (app *Application) HandlePlaylists(c echo.Context) error {
@@ -391,12 +424,26 @@ func (app *Application) HandlePlaylists(c echo.Context) error {
 		return err
 	}
 
-	data, err := app.PlaylistStore.GetMany([]string{"playlist_id", "playlist_name"}, "user_id = ?", userID)
+	owned, err := app.PlaylistStore.GetMany([]string{"playlist_id", "playlist_name"}, "user_id = ?", userID)
 	if err != nil {
 		c.Logger().Error(err)
 		return err
 	}
 
+	shared, err := app.PlaylistStore.GetMany(
+		[]string{"playlist_id", "playlist_name"},
+		"playlist_id IN (SELECT playlist_id FROM playlist_shares WHERE user_id = ?)", userID,
+	)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	data := map[string]any{
+		"Owned":  owned,
+		"Shared": shared,
+	}
+
 	if err := c.Render(http.StatusOK, "playlists.html", data); err != nil {
 		c.Logger().Error(err)
 		return err
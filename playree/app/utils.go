@@ -2,13 +2,19 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"net/http"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/NikhilSharmaWe/playree/blobstore"
 	"github.com/NikhilSharmaWe/playree/playree/models"
 	"github.com/NikhilSharmaWe/playree/playree/store"
 	"github.com/NikhilSharmaWe/rabbitmq"
@@ -16,12 +22,9 @@ import (
 	"github.com/gorilla/sessions"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/zmb3/spotify/v2"
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
-	"golang.org/x/oauth2"
 	"gorm.io/gorm"
 )
 
@@ -34,18 +37,56 @@ type Application struct {
 	SpotifyRedirectPath string
 	Authenticator       *spotifyauth.Authenticator
 
-	MinioClient     *minio.Client
-	MinioBucketName string
-
-	UserStore     store.UserStore
-	PlaylistStore store.PlaylistStore
-	TrackStore    store.TrackStore
-	TokenStore    store.TokenStore
-
-	CreatePlaylistResponseClient  *rabbitmq.RabbitClient
-	CreatePlaylistResponseChannel map[string]chan models.RabbitMQCreatePlaylistResponse
-	PublishingConn                *amqp.Connection
-	RabbitMQInstanceID            string
+	BlobStore blobstore.BlobStore
+
+	// AppPasswordKey encrypts the app passwords of Subsonic clients.
+	AppPasswordKey []byte
+	// WebhookKey encrypts the secrets webhook deliveries are signed with.
+	WebhookKey []byte
+	// WebhookAllowPrivate lets webhooks reach loopback and private addresses,
+	// for local test receivers during development.
+	WebhookAllowPrivate bool
+
+	// GRPCAddr is where RunGRPCServer listens, the gRPC service is off when
+	// it is empty.
+	GRPCAddr string
+
+	ReconcileInterval    time.Duration
+	ReconcileGracePeriod time.Duration
+	ReconcileDryRun      bool
+
+	QuotaMaxTracks        int64
+	QuotaMaxStoredBytes   int64
+	QuotaMaxStreamedBytes int64
+	QuotaBytesPerSecond   int64
+
+	UserStore            store.UserStore
+	PlaylistStore        store.PlaylistStore
+	TrackStore           store.TrackStore
+	UsageStore           store.UsageStore
+	PlaylistTokenStore   store.PlaylistTokenStore
+	AppPasswordStore     store.AppPasswordStore
+	AccessTokenStore     store.AccessTokenStore
+	WebhookStore         store.WebhookStore
+	WebhookDeliveryStore store.WebhookDeliveryStore
+	PlaylistShareStore   store.PlaylistShareStore
+	JobStore             store.JobStore
+	PlayStore            store.PlayStore
+	SmartPlaylistStore   store.SmartPlaylistStore
+	PlaybackStateStore   store.PlaybackStateStore
+	TokenStore           store.TokenStore
+
+	CreatePlaylistResponseClient *rabbitmq.RabbitClient
+	PublishingConn               *amqp.Connection
+	RabbitMQInstanceID           string
+
+	// jobWaiters wake up waitForJob when this instance finishes a job
+	jobWaitersMu sync.Mutex
+	jobWaiters   map[string][]chan struct{}
+
+	// webhookWake wakes up RunWebhookDeliveries when deliveries are queued
+	webhookWake   chan struct{}
+	webhookClient *http.Client
 }
 
 func NewApplication() (*Application, error) {
@@ -53,14 +94,48 @@ func NewApplication() (*Application, error) {
 
 	rc := createRedisClient()
 
-	minioServerAddr := os.Getenv("MINIO_SERVER_ADDR")
-	minioAccessKey := os.Getenv("MINIO_ACCESS_KEY")
-	minioSecretKey := os.Getenv("MINIO_SECRET_KEY")
-	minioBucketName := os.Getenv("MINIO_BUCKET_NAME")
+	blobStore, err := blobstore.NewFromEnv()
+	if err != nil {
+		return nil, err
+	}
 
-	client, err := minio.New(minioServerAddr, &minio.Options{
-		Creds: credentials.NewStaticV4(minioAccessKey, minioSecretKey, ""),
-	})
+	reconcileInterval, err := envDuration("RECONCILE_INTERVAL", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileGracePeriod, err := envDuration("RECONCILE_GRACE_PERIOD", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileDryRun, err := envBool("RECONCILE_DRY_RUN")
+	if err != nil {
+		return nil, err
+	}
+
+	webhookAllowPrivate, err := envBool("WEBHOOK_ALLOW_PRIVATE")
+	if err != nil {
+		return nil, err
+	}
+
+	quotaMaxTracks, err := envInt64("QUOTA_MAX_TRACKS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	quotaMaxStoredBytes, err := envInt64("QUOTA_MAX_STORED_BYTES", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	quotaMaxStreamedBytes, err := envInt64("QUOTA_MAX_STREAMED_BYTES", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// about 192 kbit/s, the bitrate of the best audio formats on youtube
+	quotaBytesPerSecond, err := envInt64("QUOTA_BYTES_PER_SECOND", 24000)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +165,9 @@ func NewApplication() (*Application, error) {
 		return nil, err
 	}
 
-	createPlaylistResponseClient, err := rabbitmq.CreateNewQueueReturnClient(consumingConnection, "create-playlist-response-"+instanceID, true, true)
+	// the response queue outlives the instances, responses to jobs that were
+	// running while playree restarted are still delivered
+	createPlaylistResponseClient, err := rabbitmq.CreateNewQueueReturnClient(consumingConnection, CreatePlaylistResponseQueue, true, false)
 	if err != nil {
 		return nil, err
 	}
@@ -113,113 +190,99 @@ func NewApplication() (*Application, error) {
 			spotifyauth.WithClientSecret(os.Getenv("CLIENT_SECRET")),
 		),
 
-		MinioClient:     client,
-		MinioBucketName: minioBucketName,
+		BlobStore: blobStore,
 
-		UserStore:     store.NewUserStore(db),
-		PlaylistStore: store.NewPlaylistStore(db),
-		TrackStore:    store.NewTrackStore(db),
-		TokenStore:    store.NewTokenStore(rc, "oauth_tokens"),
+		AppPasswordKey: deriveKey(os.Getenv("SECRET"), "app passwords"),
+		WebhookKey:     deriveKey(os.Getenv("SECRET"), "webhooks"),
 
-		CreatePlaylistResponseClient:  createPlaylistResponseClient,
-		CreatePlaylistResponseChannel: make(map[string]chan models.RabbitMQCreatePlaylistResponse),
-		PublishingConn:                publishingConnection,
-		RabbitMQInstanceID:            instanceID,
-	}, nil
-}
+		WebhookAllowPrivate: webhookAllowPrivate,
 
-func (app *Application) updateTokenFromClientIfNeeded(token *oauth2.Token, client *spotify.Client, userID string) error {
-	updatedToken, err := client.Token()
-	if err != nil {
-		return err
-	}
+		GRPCAddr: os.Getenv("GRPC_ADDR"),
 
-	if updatedToken.AccessToken != token.AccessToken {
-		if err := app.TokenStore.Update(context.Background(), userID, updatedToken); err != nil {
-			return err
-		}
-	}
+		ReconcileInterval:    reconcileInterval,
+		ReconcileGracePeriod: reconcileGracePeriod,
+		ReconcileDryRun:      reconcileDryRun,
 
-	return nil
+		QuotaMaxTracks:        quotaMaxTracks,
+		QuotaMaxStoredBytes:   quotaMaxStoredBytes,
+		QuotaMaxStreamedBytes: quotaMaxStreamedBytes,
+		QuotaBytesPerSecond:   quotaBytesPerSecond,
+
+		UserStore:            store.NewUserStore(db),
+		PlaylistStore:        store.NewPlaylistStore(db),
+		TrackStore:           store.NewTrackStore(db),
+		UsageStore:           store.NewUsageStore(db),
+		PlaylistTokenStore:   store.NewPlaylistTokenStore(db),
+		AppPasswordStore:     store.NewAppPasswordStore(db),
+		AccessTokenStore:     store.NewAccessTokenStore(db),
+		WebhookStore:         store.NewWebhookStore(db),
+		WebhookDeliveryStore: store.NewWebhookDeliveryStore(db),
+		PlaylistShareStore:   store.NewPlaylistShareStore(db),
+		JobStore:             store.NewJobStore(db),
+		PlayStore:            store.NewPlayStore(db),
+		SmartPlaylistStore:   store.NewSmartPlaylistStore(db),
+		PlaybackStateStore:   store.NewPlaybackStateStore(db),
+		TokenStore:           store.NewTokenStore(rc, "oauth_tokens"),
+
+		CreatePlaylistResponseClient: createPlaylistResponseClient,
+		PublishingConn:               publishingConnection,
+		RabbitMQInstanceID:           instanceID,
+
+		jobWaiters: make(map[string][]chan struct{}),
+
+		webhookWake:   make(chan struct{}, 1),
+		webhookClient: newWebhookClient(webhookAllowPrivate),
+	}, nil
 }
 
-func (app *Application) handleAfterPlaylistCreated(playlist *models.PlaylistsDBModel) error {
-	db := app.TrackStore.DB()
-	return db.Transaction(func(tx *gorm.DB) error {
-		playlistStore := store.NewPlaylistStore(db)
-		tokenStore := store.NewTrackStore(db)
+func (app *Application) handleAfterPlaylistCreated(tx *gorm.DB, playlist *models.PlaylistsDBModel, tracksData []*models.Track, created []models.CreatedTrack) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		playlistStore := store.NewPlaylistStore(tx)
+		trackStore := store.NewTrackStore(tx)
 
 		if err := playlistStore.Create(*playlist); err != nil {
 			return err
 		}
 
-		data, err := app.generatePresignedURIsForPlaylistTracks(playlist.PlaylistID)
+		tracks, err := createdTrackModels(playlist.PlaylistID, playlist.UserID, tracksData, created, 0)
 		if err != nil {
 			return err
 		}
 
-		tracks := []models.TrackDBModel{}
-		for key, uri := range data {
-			tracks = append(tracks, models.TrackDBModel{
-				PlaylistID: playlist.PlaylistID,
-				TrackKey:   key,
-				TrackURI:   uri,
-			})
+		if len(tracks) == 0 {
+			return nil
 		}
 
-		return tokenStore.CreateInBatches(tracks)
+		return trackStore.CreateInBatches(tracks)
 	})
 }
 
-func (app *Application) generatePresignedURIsForPlaylistTracks(playreePlaylistID string) (map[string]string, error) {
-	data := make(map[string]string)
-
-	keys, err := app.getListOfAllFiles(playreePlaylistID)
+// serveObject proxies an object from storage. Range requests, ETag and
+// conditional GETs are handled by http.ServeContent, so the browser can seek
+// without ever seeing a storage URL.
+func (app *Application) serveObject(c echo.Context, key string) error {
+	obj, info, err := app.BlobStore.Get(c.Request().Context(), key, blobstore.GetOptions{})
 	if err != nil {
-		return nil, err
-	}
-
-	for _, key := range keys {
-		url, err := app.MinioClient.PresignedGetObject(context.Background(), "playree-playlists", key, 7*24*time.Hour, nil)
-		if err != nil {
-			return nil, err
+		if errors.Is(err, blobstore.ErrNotExist) {
+			return echo.NewHTTPError(http.StatusNotFound, models.ErrTrackNotExists)
 		}
 
-		data[key] = url.String()
+		c.Logger().Error(err)
+		return err
 	}
 
-	return data, nil
-}
-
-func (app *Application) shouldUpdatePresignedURIs(playreePlaylistID string) (bool, error) {
-	now := time.Now()
-	fiveDaysAgo := now.AddDate(0, 0, -5)
+	defer obj.Close()
 
-	exists, err := app.TrackStore.IsExists("playlist_id = ? AND inserted_at <= ?", playreePlaylistID, fiveDaysAgo)
-	if err != nil {
-		return false, err
+	header := c.Response().Header()
+	if info.ContentType != "" {
+		header.Set(echo.HeaderContentType, info.ContentType)
 	}
+	header.Set("ETag", fmt.Sprintf("%q", info.ETag))
+	header.Set("Cache-Control", "private, max-age=86400")
 
-	return exists, nil
-}
-
-func (app *Application) getListOfAllFiles(playreePlaylistID string) ([]string, error) {
-	objKeys := []string{}
+	http.ServeContent(c.Response(), c.Request(), path.Base(key), info.LastModified, obj)
 
-	objectCh := app.MinioClient.ListObjects(context.Background(), app.MinioBucketName, minio.ListObjectsOptions{
-		Prefix:    playreePlaylistID,
-		Recursive: true,
-	})
-
-	for object := range objectCh {
-		if object.Err != nil {
-			return objKeys, object.Err
-		}
-
-		objKeys = append(objKeys, object.Key)
-	}
-
-	return objKeys, nil
+	return nil
 }
 
 func (app *Application) alreadyLoggedIn(c echo.Context) bool {
@@ -242,29 +305,41 @@ func (app *Application) alreadyLoggedIn(c echo.Context) bool {
 	return false
 }
 
-func getNameAndTracksFromPlaylist(client *spotify.Client, playlistID string) ([]*models.Track, string, error) {
+// getNameAndTracksFromPlaylist returns the tracks, the name and the artwork
+// url of a spotify playlist.
+func getNameAndTracksFromPlaylist(client *spotify.Client, playlistID string) ([]*models.Track, string, string, error) {
 	data := []*models.Track{}
 
 	playlist, err := client.GetPlaylist(context.Background(), spotify.ID(playlistID))
 	if err != nil {
-		return nil, "", err
+		return nil, "", "", err
 	}
 
 	for _, track := range playlist.Tracks.Tracks {
-		artists := "$"
+		data = append(data, spotifyTrackToModel(track.Track.SimpleTrack, track.Track.Album.Name))
+	}
 
-		for _, artist := range track.Track.SimpleTrack.Artists {
-			artists = artists + artist.Name + ", "
-		}
-		artists = artists[:len(artists)-2] + "$"
+	artworkURL := ""
+	if len(playlist.Images) > 0 {
+		artworkURL = playlist.Images[0].URL
+	}
+
+	return data, playlist.Name, artworkURL, nil
+}
 
-		data = append(data, &models.Track{
-			Name:    track.Track.Name,
-			Artists: artists,
-		})
+func spotifyTrackToModel(track spotify.SimpleTrack, album string) *models.Track {
+	artists := []string{}
+	for _, artist := range track.Artists {
+		artists = append(artists, artist.Name)
 	}
 
-	return data, playlist.Name, nil
+	return &models.Track{
+		Name:           track.Name,
+		Artists:        strings.Join(artists, ", "),
+		Album:          album,
+		DurationMs:     int64(track.Duration),
+		SpotifyTrackID: track.ID.String(),
+	}
 }
 
 func setSession(c echo.Context, keyValues map[string]any) error {
@@ -317,3 +392,45 @@ type Template struct {
 func (t *Template) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
 	return t.templates.ExecuteTemplate(w, name, data)
 }
+
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return d, nil
+}
+
+func envBool(key string) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return b, nil
+}
+
+func envInt64(key string, def int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return n, nil
+}
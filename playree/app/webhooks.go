@@ -0,0 +1,547 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Webhook events.
+const (
+	EventJobStarted     = "job.started"
+	EventJobFinished    = "job.finished"
+	EventJobFailed      = "job.failed"
+	EventPlaylistSynced = "playlist.synced"
+
+	// eventPing is sent by the test button of the webhook page, whatever the
+	// webhook subscribed to
+	eventPing = "ping"
+)
+
+var webhookEvents = []string{EventJobStarted, EventJobFinished, EventJobFailed, EventPlaylistSynced}
+
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryFailed    = "failed"
+)
+
+const (
+	webhookTimeout      = 10 * time.Second
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 20
+
+	// a failed delivery is retried after 30s, 1m, 2m, 4m and 8m
+	webhookMaxAttempts = 6
+	webhookRetryBase   = 30 * time.Second
+)
+
+// webhookEvent is the body of a delivery.
+type webhookEvent struct {
+	ID        string           `json:"id"`
+	Event     string           `json:"event"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      webhookEventData `json:"data"`
+}
+
+type webhookEventData struct {
+	Job      *models.JobDBModel       `json:"job,omitempty"`
+	Playlist *models.PlaylistsDBModel `json:"playlist,omitempty"`
+}
+
+type webhooksPage struct {
+	Webhooks  []models.WebhookDBModel
+	Events    []string
+	NewSecret string
+}
+
+type webhookPage struct {
+	Webhook    *models.WebhookDBModel
+	Deliveries []models.WebhookDeliveryDBModel
+}
+
+func (app *Application) HandleWebhooks(c echo.Context) error {
+	return app.renderWebhooks(c, "")
+}
+
+// HandleCreateWebhook registers a webhook for the checked events. Its signing
+// secret is shown once, right after it is created.
+func (app *Application) HandleCreateWebhook(c echo.Context) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidRequest)
+	}
+
+	webhookURL := strings.TrimSpace(form.Get("url"))
+	if !validWebhookURL(webhookURL, app.WebhookAllowPrivate) {
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrInvalidWebhookURL)
+	}
+
+	events, err := parseWebhookEvents(form["event"])
+	if err != nil {
+		return app.httpError(c, err)
+	}
+
+	secret, err := newPlaylistToken()
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	encrypted, err := encryptSecret(app.WebhookKey, secret)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := app.WebhookStore.Create(models.WebhookDBModel{
+		ID:     uuid.NewString(),
+		UserID: userID,
+		URL:    webhookURL,
+		Events: events,
+		Secret: encrypted,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return app.renderWebhooks(c, secret)
+}
+
+func (app *Application) HandleDeleteWebhook(c echo.Context) error {
+	webhook, err := app.getWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	if err := app.WebhookStore.Delete("id = ?", webhook.ID); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/webhooks")
+}
+
+// HandleWebhook shows the delivery log of a webhook.
+func (app *Application) HandleWebhook(c echo.Context) error {
+	webhook, err := app.getWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := app.WebhookDeliveryStore.GetPage("created_at DESC", 50, 0, "webhook_id = ?", webhook.ID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := c.Render(http.StatusOK, "webhook.html", webhookPage{
+		Webhook:    webhook,
+		Deliveries: deliveries,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// HandlePingWebhook queues a ping event, to try a receiver out.
+func (app *Application) HandlePingWebhook(c echo.Context) error {
+	webhook, err := app.getWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	if err := app.queueDeliveries(eventPing, []models.WebhookDBModel{*webhook}, webhookEventData{}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/webhooks/"+webhook.ID)
+}
+
+func (app *Application) renderWebhooks(c echo.Context, newSecret string) error {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	webhooks, err := app.WebhookStore.GetManyOrdered("created_at DESC", "user_id = ?", userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := c.Render(http.StatusOK, "webhooks.html", webhooksPage{
+		Webhooks:  webhooks,
+		Events:    webhookEvents,
+		NewSecret: newSecret,
+	}); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// getWebhook loads the webhook_id route parameter, which has to be one of
+// the visitor's webhooks.
+func (app *Application) getWebhook(c echo.Context) (*models.WebhookDBModel, error) {
+	userID, err := getContext(c, "user_id")
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, err
+	}
+
+	webhook, err := app.WebhookStore.GetOne("id = ? AND user_id = ?", c.Param("webhook_id"), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, models.ErrWebhookNotExists)
+		}
+
+		c.Logger().Error(err)
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// emitJobEvent sends event to the webhooks of the user who started job and,
+// for jobs on an existing playlist, of the playlist owner.
+func (app *Application) emitJobEvent(event string, job *models.JobDBModel, playlist *models.PlaylistsDBModel) {
+	userIDs := []string{job.UserID}
+	if playlist != nil && playlist.UserID != job.UserID {
+		userIDs = append(userIDs, playlist.UserID)
+	}
+
+	app.emitEvent(event, userIDs, webhookEventData{Job: job, Playlist: playlist})
+}
+
+// emitEvent queues event for the webhooks of userIDs that subscribed to it.
+// Errors are only logged, events never fail the work they report.
+func (app *Application) emitEvent(event string, userIDs []string, data webhookEventData) {
+	webhooks, err := app.WebhookStore.GetManyOrdered("created_at", "user_id IN ?", userIDs)
+	if err != nil {
+		log.Println("ERROR: LOADING WEBHOOKS: ", err)
+		return
+	}
+
+	subscribed := []models.WebhookDBModel{}
+	for _, webhook := range webhooks {
+		if slices.Contains(strings.Split(webhook.Events, ","), event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+
+	if len(subscribed) == 0 {
+		return
+	}
+
+	if err := app.queueDeliveries(event, subscribed, data); err != nil {
+		log.Println("ERROR: QUEUEING WEBHOOK DELIVERIES: ", err)
+	}
+}
+
+func (app *Application) queueDeliveries(event string, webhooks []models.WebhookDBModel, data webhookEventData) error {
+	payload, err := json.Marshal(webhookEvent{
+		ID:        uuid.NewString(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := []models.WebhookDeliveryDBModel{}
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, models.WebhookDeliveryDBModel{
+			ID:            uuid.NewString(),
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        deliveryPending,
+			CreatedAt:     time.Now(),
+			NextAttemptAt: time.Now(),
+		})
+	}
+
+	if err := app.WebhookDeliveryStore.CreateInBatches(deliveries); err != nil {
+		return err
+	}
+
+	select {
+	case app.webhookWake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// RunWebhookDeliveries sends queued webhook deliveries until ctx ends. The
+// queue lives in the database, so deliveries survive restarts and every
+// instance helps sending them.
+func (app *Application) RunWebhookDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := app.deliverWebhooks(ctx); err != nil {
+			log.Println("ERROR: DELIVERING WEBHOOKS: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-app.webhookWake:
+		}
+	}
+}
+
+// deliverWebhooks sends the deliveries that are due, a batch at a time.
+func (app *Application) deliverWebhooks(ctx context.Context) error {
+	for ctx.Err() == nil {
+		// the lease outlasts a batch, whose requests run in parallel
+		deliveries, err := app.WebhookDeliveryStore.Claim(webhookBatchSize, 2*webhookTimeout,
+			"status = ? AND next_attempt_at <= ?", deliveryPending, time.Now(),
+		)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+
+			go func(delivery models.WebhookDeliveryDBModel) {
+				defer wg.Done()
+
+				if err := app.deliverWebhook(ctx, delivery); err != nil {
+					log.Println("ERROR: DELIVERING WEBHOOK: ", err)
+				}
+			}(delivery)
+		}
+
+		wg.Wait()
+
+		if len(deliveries) < webhookBatchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// deliverWebhook makes one attempt at a delivery and records its outcome.
+func (app *Application) deliverWebhook(ctx context.Context, delivery models.WebhookDeliveryDBModel) error {
+	webhook, err := app.WebhookStore.GetOne("id = ?", delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	secret, err := decryptSecret(app.WebhookKey, webhook.Secret)
+	if err != nil {
+		return err
+	}
+
+	status, sendErr := app.sendWebhook(ctx, webhook.URL, secret, delivery)
+	attempts := delivery.Attempts + 1
+
+	updateMap := map[string]any{
+		"attempts":        attempts,
+		"response_status": status,
+		"error":           "",
+	}
+
+	switch {
+	case sendErr == nil:
+		updateMap["status"] = deliveryDelivered
+		updateMap["delivered_at"] = time.Now()
+	case attempts >= webhookMaxAttempts:
+		updateMap["status"] = deliveryFailed
+		updateMap["error"] = sendErr.Error()
+	default:
+		updateMap["error"] = sendErr.Error()
+		updateMap["next_attempt_at"] = time.Now().Add(webhookRetryBase << (attempts - 1))
+	}
+
+	return app.WebhookDeliveryStore.Update(updateMap, "id = ?", delivery.ID)
+}
+
+// sendWebhook posts the payload of delivery to webhookURL, signed with
+// secret. Any 2xx response counts as delivered.
+func (app *Application) sendWebhook(ctx context.Context, webhookURL, secret string, delivery models.WebhookDeliveryDBModel) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "playree-webhooks")
+	req.Header.Set("X-Playree-Event", delivery.Event)
+	req.Header.Set("X-Playree-Delivery", delivery.ID)
+	req.Header.Set("X-Playree-Signature", signWebhookPayload(secret, delivery.Payload))
+
+	resp, err := app.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	// drain a little of the body, so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload is the X-Playree-Signature of payload: the hex HMAC-SHA256
+// of the body with the webhook's secret.
+func signWebhookPayload(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validWebhookURL checks a webhook URL when it is registered. Hosts that
+// are names are only checked once they are resolved, by newWebhookClient.
+func validWebhookURL(raw string, allowPrivate bool) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+
+	if allowPrivate {
+		return true
+	}
+
+	if strings.EqualFold(u.Hostname(), "localhost") {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(u.Hostname())
+
+	return err != nil || publicAddr(addr)
+}
+
+// errWebhookAddress is the delivery error of webhooks that resolve to an
+// address they may not reach.
+var errWebhookAddress = errors.New("webhook address is not public")
+
+// webhookBlockedPrefixes are ranges that are not public but not covered by
+// the netip.Addr methods either.
+var webhookBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// publicAddr reports whether webhooks may be delivered to addr: deliveries
+// and their logged results must not let users probe the server's network.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range webhookBlockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newWebhookClient is the client deliveries are sent with. Unless
+// allowPrivate is set, every connection it opens is checked with publicAddr
+// after the host is resolved, so redirects and DNS rebinding cannot reach
+// private addresses either. It never uses a proxy, the proxy would connect
+// on its behalf.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+
+			if !publicAddr(addrPort.Addr()) {
+				return errWebhookAddress
+			}
+
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+	}
+}
+
+// parseWebhookEvents checks the events a webhook subscribes to and joins them
+// for the events column.
+func parseWebhookEvents(events []string) (string, error) {
+	if len(events) == 0 {
+		return "", models.ErrInvalidWebhookEvents
+	}
+
+	for _, event := range events {
+		if !slices.Contains(webhookEvents, event) {
+			return "", models.ErrInvalidWebhookEvents
+		}
+	}
+
+	return strings.Join(events, ","), nil
+}
@@ -0,0 +1,266 @@
+// Package client is a Go client for the playree JSON API, see API.md. It
+// authenticates with a personal access token.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pageSize is the page size of the All* helpers, the largest the API allows.
+const pageSize = 500
+
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// New returns a client for the playree server at baseURL, e.g.
+// http://localhost:8080, authenticated with a personal access token.
+func New(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{},
+	}
+}
+
+// Error is an error response of the API.
+type Error struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("playree: %d %s", e.Status, e.Message)
+}
+
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, "/api/v1/me", nil, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (c *Client) ListPlaylists(ctx context.Context, limit, offset int) (*Page[Playlist], error) {
+	var page Page[Playlist]
+	if err := c.do(ctx, http.MethodGet, "/api/v1/playlists"+pageQuery(limit, offset), nil, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// AllPlaylists lists every playlist the user owns or that is shared with
+// them.
+func (c *Client) AllPlaylists(ctx context.Context) ([]Playlist, error) {
+	return all(ctx, func(ctx context.Context, offset int) (*Page[Playlist], error) {
+		return c.ListPlaylists(ctx, pageSize, offset)
+	})
+}
+
+func (c *Client) GetPlaylist(ctx context.Context, playlistID string) (*Playlist, error) {
+	var playlist Playlist
+	if err := c.do(ctx, http.MethodGet, "/api/v1/playlists/"+url.PathEscape(playlistID), nil, &playlist); err != nil {
+		return nil, err
+	}
+
+	return &playlist, nil
+}
+
+// CreatePlaylist starts importing a Spotify playlist, given as link, URI or
+// id. The playlist exists once the returned job is done, see WaitJob.
+func (c *Client) CreatePlaylist(ctx context.Context, spotifyPlaylist string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodPost, "/api/v1/playlists", map[string]string{"spotify_playlist": spotifyPlaylist}, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// SyncPlaylist starts adding the tracks that are new in the Spotify playlist
+// the playlist was imported from.
+func (c *Client) SyncPlaylist(ctx context.Context, playlistID string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodPost, "/api/v1/playlists/"+url.PathEscape(playlistID)+"/sync", nil, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (c *Client) ListTracks(ctx context.Context, playlistID string, limit, offset int) (*Page[Track], error) {
+	var page Page[Track]
+	if err := c.do(ctx, http.MethodGet, "/api/v1/playlists/"+url.PathEscape(playlistID)+"/tracks"+pageQuery(limit, offset), nil, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// AllTracks lists every track of a playlist, in playlist order.
+func (c *Client) AllTracks(ctx context.Context, playlistID string) ([]Track, error) {
+	return all(ctx, func(ctx context.Context, offset int) (*Page[Track], error) {
+		return c.ListTracks(ctx, playlistID, pageSize, offset)
+	})
+}
+
+func (c *Client) GetJob(ctx context.Context, jobID string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(jobID), nil, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// WaitJob polls a job every interval until it is done or failed, or ctx
+// ends. progress, if not nil, is called with every state of the job.
+func (c *Client) WaitJob(ctx context.Context, jobID string, interval time.Duration, progress func(*Job)) (*Job, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job, err := c.GetJob(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+
+		if progress != nil {
+			progress(job)
+		}
+
+		if job.Status != JobPending {
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DownloadTrack writes the audio of a track to w.
+func (c *Client) DownloadTrack(ctx context.Context, trackID string, w io.Writer) (int64, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/stream/"+url.PathEscape(trackID), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	return io.Copy(w, resp.Body)
+}
+
+// do sends a request with body as JSON and decodes the response into out.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send sends a request and turns error responses into *Error.
+func (c *Client) send(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	var errBody struct {
+		Error Error `json:"error"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&errBody); err != nil || errBody.Error.Status == 0 {
+		return nil, &Error{Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	}
+
+	return nil, &errBody.Error
+}
+
+// all follows the pages of a list.
+func all[T any](ctx context.Context, list func(ctx context.Context, offset int) (*Page[T], error)) ([]T, error) {
+	items := []T{}
+	offset := 0
+
+	for {
+		page, err := list(ctx, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, page.Items...)
+
+		if page.NextOffset == nil {
+			return items, nil
+		}
+
+		offset = *page.NextOffset
+	}
+}
+
+func pageQuery(limit, offset int) string {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	if len(query) == 0 {
+		return ""
+	}
+
+	return "?" + query.Encode()
+}
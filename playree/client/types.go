@@ -0,0 +1,73 @@
+package client
+
+import "time"
+
+// Job states.
+const (
+	JobPending = "pending"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Page is one page of a list. NextOffset is nil on the last page.
+type Page[T any] struct {
+	Items      []T  `json:"items"`
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	NextOffset *int `json:"next_offset"`
+}
+
+type User struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Usage    Usage  `json:"usage"`
+}
+
+// Usage is the usage of a user against their quotas, a zero maximum means
+// unlimited.
+type Usage struct {
+	Tracks           int64 `json:"tracks"`
+	StoredBytes      int64 `json:"stored_bytes"`
+	StreamedBytes    int64 `json:"streamed_bytes"`
+	MaxTracks        int64 `json:"max_tracks"`
+	MaxStoredBytes   int64 `json:"max_stored_bytes"`
+	MaxStreamedBytes int64 `json:"max_streamed_bytes"`
+}
+
+type Playlist struct {
+	PlaylistID        string `json:"playlist_id"`
+	PlaylistName      string `json:"playlist_name"`
+	UserID            string `json:"user_id"`
+	ArtworkURL        string `json:"artwork_url,omitempty"`
+	SpotifyPlaylistID string `json:"spotify_playlist_id,omitempty"`
+	Role              string `json:"role"`
+	TrackCount        *int64 `json:"track_count,omitempty"`
+}
+
+type Track struct {
+	TrackID        string    `json:"track_id"`
+	PlaylistID     string    `json:"playlist_id"`
+	Title          string    `json:"title"`
+	Artists        string    `json:"artists"`
+	Album          string    `json:"album,omitempty"`
+	DurationMs     int64     `json:"duration_ms"`
+	Position       int       `json:"position"`
+	SpotifyTrackID string    `json:"spotify_track_id,omitempty"`
+	SizeBytes      int64     `json:"size_bytes,omitempty"`
+	AddedBy        string    `json:"added_by,omitempty"`
+	InsertedAt     time.Time `json:"inserted_at"`
+}
+
+type Job struct {
+	JobID             string     `json:"job_id"`
+	PlaylistID        string     `json:"playlist_id"`
+	UserID            string     `json:"user_id"`
+	Kind              string     `json:"kind"`
+	Status            string     `json:"status"`
+	Error             string     `json:"error,omitempty"`
+	PlaylistName      string     `json:"playlist_name,omitempty"`
+	SpotifyPlaylistID string     `json:"spotify_playlist_id,omitempty"`
+	TrackCount        int        `json:"track_count"`
+	CreatedAt         time.Time  `json:"created_at"`
+	FinishedAt        *time.Time `json:"finished_at,omitempty"`
+}
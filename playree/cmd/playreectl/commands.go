@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/client"
+)
+
+func runLogin(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: playreectl login <token>")
+	}
+
+	cli.config.Token = args[0]
+	c := client.New(cli.config.Server, cli.config.Token)
+
+	user, err := c.Me(ctx)
+	if err != nil {
+		return err
+	}
+
+	path, err := saveConfig(cli.config)
+	if err != nil {
+		return err
+	}
+
+	if cli.json {
+		return printJSON(user)
+	}
+
+	fmt.Printf("logged in to %s as %s, saved to %s\n", cli.config.Server, user.Username, path)
+	return nil
+}
+
+func runWhoami(ctx context.Context, cli *cli, args []string) error {
+	user, err := cli.client.Me(ctx)
+	if err != nil {
+		return err
+	}
+
+	if cli.json {
+		return printJSON(user)
+	}
+
+	fmt.Printf("%s (%s)\n", user.Username, user.UserID)
+	fmt.Printf("tracks:   %d%s\n", user.Usage.Tracks, limit(user.Usage.MaxTracks, formatCount))
+	fmt.Printf("stored:   %s%s\n", formatBytes(user.Usage.StoredBytes), limit(user.Usage.MaxStoredBytes, formatBytes))
+	fmt.Printf("streamed: %s%s this month\n", formatBytes(user.Usage.StreamedBytes), limit(user.Usage.MaxStreamedBytes, formatBytes))
+	return nil
+}
+
+func runPlaylists(ctx context.Context, cli *cli, args []string) error {
+	playlists, err := cli.client.AllPlaylists(ctx)
+	if err != nil {
+		return err
+	}
+
+	if cli.json {
+		return printJSON(playlists)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tROLE")
+	for _, playlist := range playlists {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", playlist.PlaylistID, playlist.PlaylistName, playlist.Role)
+	}
+
+	return w.Flush()
+}
+
+func runTracks(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: playreectl tracks <playlist id>")
+	}
+
+	tracks, err := cli.client.AllTracks(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if cli.json {
+		return printJSON(tracks)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tID\tARTISTS\tTITLE\tLENGTH")
+	for i, track := range tracks {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, track.TrackID, track.Artists, track.Title, formatDuration(track.DurationMs))
+	}
+
+	return w.Flush()
+}
+
+func runImport(ctx context.Context, cli *cli, args []string) error {
+	fs := newFlagSet("import [-detach] <spotify playlist link or id>")
+	detach := fs.Bool("detach", false, "print the job and return without waiting for it")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	job, err := cli.client.CreatePlaylist(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return cli.followJob(ctx, job, *detach)
+}
+
+func runSync(ctx context.Context, cli *cli, args []string) error {
+	fs := newFlagSet("sync [-detach] <playlist id>")
+	detach := fs.Bool("detach", false, "print the job and return without waiting for it")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	job, err := cli.client.SyncPlaylist(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return cli.followJob(ctx, job, *detach)
+}
+
+func runJob(ctx context.Context, cli *cli, args []string) error {
+	fs := newFlagSet("job [-wait] <job id>")
+	wait := fs.Bool("wait", false, "wait until the job is done or failed")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	job, err := cli.client.GetJob(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return cli.followJob(ctx, job, !*wait)
+}
+
+// followJob waits for job unless detach is set, reporting its progress on
+// stderr, and prints it. A failed job is an error, so scripts can check the
+// exit code.
+func (cli *cli) followJob(ctx context.Context, job *client.Job, detach bool) error {
+	if !detach && job.Status == client.JobPending {
+		started := time.Now()
+		progressf("job %s: %s %d tracks ", job.JobID, job.Kind, job.TrackCount)
+
+		var err error
+		job, err = cli.client.WaitJob(ctx, job.JobID, jobPollInterval, func(*client.Job) {
+			progressf(".")
+		})
+		progressf(" %s after %s\n", statusOf(job), time.Since(started).Round(time.Second))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if cli.json {
+		if err := printJSON(job); err != nil {
+			return err
+		}
+	} else {
+		printJob(job)
+	}
+
+	if job.Status == client.JobFailed {
+		return fmt.Errorf("job %s failed: %s", job.JobID, job.Error)
+	}
+
+	return nil
+}
+
+func printJob(job *client.Job) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "job\t%s\n", job.JobID)
+	fmt.Fprintf(w, "kind\t%s\n", job.Kind)
+	fmt.Fprintf(w, "status\t%s\n", job.Status)
+	fmt.Fprintf(w, "playlist\t%s\n", job.PlaylistID)
+	fmt.Fprintf(w, "tracks\t%d\n", job.TrackCount)
+	if job.Error != "" {
+		fmt.Fprintf(w, "error\t%s\n", job.Error)
+	}
+	w.Flush()
+}
+
+func statusOf(job *client.Job) string {
+	if job == nil {
+		return "interrupted"
+	}
+
+	return job.Status
+}
+
+type downloadedTrack struct {
+	TrackID string `json:"track_id"`
+	File    string `json:"file"`
+	Skipped bool   `json:"skipped"`
+}
+
+// runDownload downloads every track of a playlist into a directory, named
+// like in the ZIP download of the web interface, and writes an M3U playlist
+// next to them. Files that are already complete are skipped, so an
+// interrupted download can be resumed.
+func runDownload(ctx context.Context, cli *cli, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: playreectl download <playlist id> [directory]")
+	}
+
+	playlist, err := cli.client.GetPlaylist(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	tracks, err := cli.client.AllTracks(ctx, playlist.PlaylistID)
+	if err != nil {
+		return err
+	}
+
+	dir := sanitizeFileName(playlist.PlaylistName)
+	if len(args) == 2 {
+		dir = args[1]
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	downloaded := []downloadedTrack{}
+	m3u := strings.Builder{}
+	m3u.WriteString("#EXTM3U\n")
+
+	for i, track := range tracks {
+		name := fmt.Sprintf("%02d - %s - %s.mp3", i+1, sanitizeFileName(track.Artists), sanitizeFileName(track.Title))
+		path := filepath.Join(dir, name)
+
+		skipped, err := cli.downloadTrack(ctx, track, path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		if skipped {
+			progressf("[%d/%d] %s (already there)\n", i+1, len(tracks), name)
+		} else {
+			progressf("[%d/%d] %s\n", i+1, len(tracks), name)
+		}
+
+		downloaded = append(downloaded, downloadedTrack{TrackID: track.TrackID, File: path, Skipped: skipped})
+		fmt.Fprintf(&m3u, "#EXTINF:%d,%s - %s\n%s\n", track.DurationMs/1000, track.Artists, track.Title, name)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, sanitizeFileName(playlist.PlaylistName)+".m3u"), []byte(m3u.String()), 0o644); err != nil {
+		return err
+	}
+
+	if cli.json {
+		return printJSON(downloaded)
+	}
+
+	fmt.Printf("downloaded %d tracks to %s\n", len(tracks), dir)
+	return nil
+}
+
+// downloadTrack downloads a track to path through a temporary file, so path
+// only ever holds complete tracks.
+func (cli *cli) downloadTrack(ctx context.Context, track client.Track, path string) (bool, error) {
+	if info, err := os.Stat(path); err == nil && (track.SizeBytes == 0 || info.Size() == track.SizeBytes) {
+		return true, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".playreectl-*")
+	if err != nil {
+		return false, err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := cli.client.DownloadTrack(ctx, track.TrackID, tmp); err != nil {
+		tmp.Close()
+		return false, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+
+	return false, os.Rename(tmp.Name(), path)
+}
+
+// sanitizeFileName makes s safe to use as a file name on common file systems,
+// the same way the server names the files of its ZIP downloads.
+func sanitizeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+
+		return r
+	}, s)
+
+	s = strings.Trim(strings.TrimSpace(s), ".")
+	if s == "" {
+		return "untitled"
+	}
+
+	return s
+}
+
+func formatDuration(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatCount(n int64) string {
+	return fmt.Sprint(n)
+}
+
+// limit formats a quota maximum, zero means unlimited.
+func limit(max int64, format func(int64) string) string {
+	if max == 0 {
+		return ""
+	}
+
+	return " of " + format(max)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// config is saved by login, PLAYREE_SERVER and PLAYREE_TOKEN override it.
+type config struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "playree", "config.json"), nil
+}
+
+func loadConfig() (config, error) {
+	cfg := config{Server: "http://localhost:8080"}
+
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, err
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	if server := os.Getenv("PLAYREE_SERVER"); server != "" {
+		cfg.Server = server
+	}
+
+	if token := os.Getenv("PLAYREE_TOKEN"); token != "" {
+		cfg.Token = token
+	}
+
+	return cfg, nil
+}
+
+// saveConfig writes cfg readable only by the user, it holds the token.
+func saveConfig(cfg config) (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return path, os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,170 @@
+// playreectl is a command-line client for playree. It talks to the JSON API
+// with a personal access token, created on the Access Tokens page.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/client"
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, cli *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"login": {
+		usage:       "login <token>",
+		description: "check a personal access token and save it with -server",
+		run:         runLogin,
+	},
+	"whoami": {
+		usage:       "whoami",
+		description: "show the user and their quota usage",
+		run:         runWhoami,
+	},
+	"playlists": {
+		usage:       "playlists",
+		description: "list your playlists and the ones shared with you",
+		run:         runPlaylists,
+	},
+	"tracks": {
+		usage:       "tracks <playlist id>",
+		description: "list the tracks of a playlist",
+		run:         runTracks,
+	},
+	"import": {
+		usage:       "import [-detach] <spotify playlist link or id>",
+		description: "import a Spotify playlist and follow the job",
+		run:         runImport,
+	},
+	"sync": {
+		usage:       "sync [-detach] <playlist id>",
+		description: "add the tracks that are new in the Spotify playlist and follow the job",
+		run:         runSync,
+	},
+	"job": {
+		usage:       "job [-wait] <job id>",
+		description: "show a job",
+		run:         runJob,
+	},
+	"download": {
+		usage:       "download <playlist id> [directory]",
+		description: "download the tracks and an M3U playlist, files already there are skipped",
+		run:         runDownload,
+	},
+}
+
+// cli is the state shared by the commands.
+type cli struct {
+	config config
+	json   bool
+	client *client.Client
+}
+
+func main() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fatal(err)
+	}
+
+	flag.StringVar(&cfg.Server, "server", cfg.Server, "playree server URL, also read from PLAYREE_SERVER")
+	flag.StringVar(&cfg.Token, "token", cfg.Token, "personal access token, also read from PLAYREE_TOKEN")
+	jsonOutput := flag.Bool("json", false, "print results as JSON")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "playreectl: unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := &cli{
+		config: cfg,
+		json:   *jsonOutput,
+		client: client.New(cfg.Server, cfg.Token),
+	}
+
+	if err := cmd.run(ctx, c, flag.Args()[1:]); err != nil {
+		fatal(err)
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+
+	fmt.Fprintf(out, "usage: playreectl [flags] <command> [arguments]\n\ncommands:\n")
+
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-50s %s\n", commands[name].usage, commands[name].description)
+	}
+
+	fmt.Fprintf(out, "\nflags:\n")
+	flag.PrintDefaults()
+}
+
+func fatal(err error) {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		fmt.Fprintf(os.Stderr, "playreectl: %s (HTTP %d)\n", apiErr.Message, apiErr.Status)
+	} else {
+		fmt.Fprintf(os.Stderr, "playreectl: %s\n", err)
+	}
+
+	os.Exit(1)
+}
+
+// printJSON prints v, for -json.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// progressf reports progress on stderr, so stdout stays parseable.
+func progressf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// newFlagSet parses the flags of a command, usage is its usage line.
+func newFlagSet(usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(strings.Fields(usage)[0], flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: playreectl %s\n", usage)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// jobPollInterval is how often followed jobs are polled.
+const jobPollInterval = 2 * time.Second
@@ -1,3 +1,7 @@
+-- The schema of a new database. Existing databases are upgraded by running
+-- the files in migrations/ that have not been run yet, in order; every
+-- change to this file needs a migration there.
+
 CREATE TABLE users(
 	user_id VARCHAR(50) NOT NULL PRIMARY KEY,
 	username VARCHAR(50) NOT NULL
@@ -6,14 +10,189 @@ CREATE TABLE users(
 CREATE TABLE playlists(
 	playlist_id TEXT NOT NULL PRIMARY KEY,
 	playlist_name TEXT NOT NULL ,
-	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE
+	artwork_url TEXT NOT NULL DEFAULT '',
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	-- the imported Spotify playlist, empty for playlists imported before
+	-- syncing existed
+	spotify_playlist_id TEXT NOT NULL DEFAULT '',
+	search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', playlist_name)) STORED
 );
 
+CREATE INDEX idx_playlists_on_search_vector ON playlists USING GIN (search_vector);
+
 CREATE TABLE tracks (
-	track_key TEXT NOT NULL PRIMARY KEY,
-  	track_uri TEXT NOT NULL,
+	track_id TEXT NOT NULL PRIMARY KEY,
+	track_key TEXT NOT NULL UNIQUE,
   	playlist_id TEXT NOT NULL REFERENCES playlists(playlist_id) ON DELETE CASCADE,
-  	inserted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	title TEXT NOT NULL,
+	artists TEXT NOT NULL,
+	album TEXT NOT NULL DEFAULT '',
+	duration_ms BIGINT NOT NULL DEFAULT 0,
+	position INTEGER NOT NULL,
+	video_id TEXT NOT NULL,
+	hls_key TEXT NOT NULL DEFAULT '',
+	spotify_track_id TEXT NOT NULL DEFAULT '',
+	size_bytes BIGINT NOT NULL DEFAULT 0,
+	audio_size_bytes BIGINT NOT NULL DEFAULT 0,
+	added_by TEXT NOT NULL DEFAULT '',
+  	inserted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	-- 'simple' does not stem, titles and artists are in every language
+	search_vector TSVECTOR GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', title), 'A') ||
+		setweight(to_tsvector('simple', artists), 'B') ||
+		setweight(to_tsvector('simple', album), 'C')
+	) STORED
 );
 
 CREATE INDEX idx_tracks_on_playlist_id ON tracks(playlist_id);
+CREATE INDEX idx_tracks_on_search_vector ON tracks USING GIN (search_vector);
+
+CREATE TABLE usage (
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	period TEXT NOT NULL,
+	streamed_bytes BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, period)
+);
+
+CREATE TABLE playlist_tokens (
+	token TEXT NOT NULL PRIMARY KEY,
+	playlist_id TEXT NOT NULL REFERENCES playlists(playlist_id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_playlist_tokens_on_playlist_id ON playlist_tokens(playlist_id);
+
+-- secret is encrypted with a key derived from SECRET: Subsonic token
+-- authentication needs the plain password on the server
+CREATE TABLE app_passwords (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TIMESTAMP
+);
+
+CREATE INDEX idx_app_passwords_on_user_id ON app_passwords(user_id);
+
+-- only the sha256 of a personal access token is stored, the token itself is
+-- shown once when it is created
+CREATE TABLE access_tokens (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scope TEXT NOT NULL CHECK (scope IN ('read', 'read-write')),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_access_tokens_on_user_id ON access_tokens(user_id);
+
+CREATE TABLE playlist_shares (
+	playlist_id TEXT NOT NULL REFERENCES playlists(playlist_id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	role TEXT NOT NULL CHECK (role IN ('viewer', 'editor')),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (playlist_id, user_id)
+);
+
+CREATE INDEX idx_playlist_shares_on_user_id ON playlist_shares(user_id);
+
+-- playlist_id has no foreign key: the playlist of a create job only exists
+-- once the job is done. tracks holds the requested tracks as JSON, so any
+-- instance can finish a job.
+CREATE TABLE jobs (
+	job_id TEXT NOT NULL PRIMARY KEY,
+	playlist_id TEXT NOT NULL,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	kind TEXT NOT NULL CHECK (kind IN ('create', 'add', 'sync')),
+	status TEXT NOT NULL CHECK (status IN ('pending', 'done', 'failed')),
+	error TEXT NOT NULL DEFAULT '',
+	playlist_name TEXT NOT NULL DEFAULT '',
+	artwork_url TEXT NOT NULL DEFAULT '',
+	spotify_playlist_id TEXT NOT NULL DEFAULT '',
+	track_count INTEGER NOT NULL DEFAULT 0,
+	tracks TEXT NOT NULL DEFAULT '[]',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_jobs_on_playlist_id ON jobs(playlist_id);
+
+-- one row per player event; a play counts when it starts, completions and
+-- skips tell how it ended
+CREATE TABLE plays (
+	id BIGSERIAL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	track_id TEXT NOT NULL REFERENCES tracks(track_id) ON DELETE CASCADE,
+	playlist_id TEXT NOT NULL REFERENCES playlists(playlist_id) ON DELETE CASCADE,
+	event TEXT NOT NULL CHECK (event IN ('start', 'complete', 'skip')),
+	position_ms BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_plays_on_user_id_and_created_at ON plays(user_id, created_at);
+CREATE INDEX idx_plays_on_playlist_id ON plays(playlist_id);
+
+-- rule is a models.SmartRule as JSON, the tracks are selected from the
+-- user's library every time the playlist is opened
+CREATE TABLE smart_playlists (
+	smart_playlist_id TEXT NOT NULL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	rule TEXT NOT NULL,
+	track_limit INTEGER NOT NULL DEFAULT 50,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_smart_playlists_on_user_id ON smart_playlists(user_id);
+
+-- playlist_id has no foreign key, it names a playlist or a smart playlist
+-- depending on source
+CREATE TABLE playback_states (
+	user_id TEXT NOT NULL PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+	source TEXT NOT NULL CHECK (source IN ('playlist', 'smart')),
+	playlist_id TEXT NOT NULL,
+	track_id TEXT NOT NULL,
+	track_index INTEGER NOT NULL DEFAULT 0,
+	position_ms BIGINT NOT NULL DEFAULT 0,
+	device TEXT NOT NULL DEFAULT '',
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- secret is encrypted with a key derived from SECRET, deliveries are signed
+-- with it; events is a comma separated list
+CREATE TABLE webhooks (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	events TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhooks_on_user_id ON webhooks(user_id);
+
+-- the delivery log and the retry queue: pending deliveries are sent once
+-- next_attempt_at has passed
+CREATE TABLE webhook_deliveries (
+	id TEXT NOT NULL PRIMARY KEY,
+	webhook_id TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	event TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL CHECK (status IN ('pending', 'delivered', 'failed')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	response_status INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	delivered_at TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_on_webhook_id ON webhook_deliveries(webhook_id, created_at);
+CREATE INDEX idx_webhook_deliveries_on_next_attempt_at ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
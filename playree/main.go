@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 
@@ -17,11 +18,42 @@ func init() {
 }
 
 func main() {
+	reconcile := flag.Bool("reconcile", false, "reconcile object storage with the database once and exit")
+	dryRun := flag.Bool("dry-run", false, "with -reconcile, only report orphans without deleting them")
+	flag.Parse()
+
 	application, err := app.NewApplication()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	reconcileOpts := app.ReconcileOptions{
+		DryRun:      *dryRun || application.ReconcileDryRun,
+		GracePeriod: application.ReconcileGracePeriod,
+	}
+
+	if *reconcile {
+		report, err := application.Reconcile(context.Background(), reconcileOpts)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		report.Log()
+		return
+	}
+
+	if application.ReconcileInterval > 0 {
+		go application.RunReconcileEvery(context.Background(), application.ReconcileInterval, reconcileOpts)
+	}
+
+	go application.RunWebhookDeliveries(context.Background())
+
+	if application.GRPCAddr != "" {
+		go func() {
+			log.Fatal(application.RunGRPCServer())
+		}()
+	}
+
 	e := application.Router()
 
 	createPlaylistRespMSGBus, err := setupCreatePlaylistSvcRabbitMQForStartup(application)
@@ -30,18 +62,15 @@ func main() {
 	}
 
 	createPlaylistG, _ := errgroup.WithContext(context.Background())
-	createPlaylistG.SetLimit(50)
+	createPlaylistG.SetLimit(createPlaylistResponseWorkers)
 
 	go func() {
 		for message := range createPlaylistRespMSGBus {
 			msg := message
 
 			createPlaylistG.Go(func() error {
-				response, err := handleRabbitMQResponses(application, msg)
-				if err != nil {
+				if err := handleRabbitMQResponses(application, msg); err != nil {
 					log.Println("ERRROR: HANDLING CREATE PLAYLIST RESPONSES: ", err)
-				} else {
-					application.CreatePlaylistResponseChannel[response.PlayreePlaylistID] <- *response
 				}
 				return nil
 			})
@@ -0,0 +1,52 @@
+-- Tracks get an opaque track_id primary key and their metadata as columns.
+-- Objects keep their keys, the metadata of existing tracks is parsed from
+-- the old <playlist id>/<i>_<title>_$<artists>$.mp3 keys. Their album,
+-- duration and source video are unknown and stay empty.
+BEGIN;
+
+ALTER TABLE tracks
+	ADD COLUMN track_id TEXT,
+	ADD COLUMN title TEXT,
+	ADD COLUMN artists TEXT,
+	ADD COLUMN album TEXT NOT NULL DEFAULT '',
+	ADD COLUMN duration_ms BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN position INTEGER,
+	ADD COLUMN video_id TEXT NOT NULL DEFAULT '',
+	ADD COLUMN spotify_track_id TEXT NOT NULL DEFAULT '';
+
+WITH parsed AS (
+	SELECT track_key, regexp_match(track_key, '^[^/]+/([0-9]+)_(.*)_\$(.*)\$\.mp3$') AS m
+	FROM tracks
+), numbered AS (
+	SELECT
+		t.track_key,
+		COALESCE(p.m[2], t.track_key) AS title,
+		COALESCE(p.m[3], '') AS artists,
+		-- positions are dense and start at 0, like the ones of new tracks
+		row_number() OVER (
+			PARTITION BY t.playlist_id
+			ORDER BY COALESCE(p.m[1]::INTEGER, 0), t.inserted_at, t.track_key
+		) - 1 AS position
+	FROM tracks t
+	JOIN parsed p ON p.track_key = t.track_key
+)
+UPDATE tracks t SET
+	track_id = gen_random_uuid()::TEXT,
+	title = n.title,
+	artists = n.artists,
+	position = n.position
+FROM numbered n
+WHERE n.track_key = t.track_key;
+
+ALTER TABLE tracks
+	ALTER COLUMN track_id SET NOT NULL,
+	ALTER COLUMN title SET NOT NULL,
+	ALTER COLUMN artists SET NOT NULL,
+	ALTER COLUMN position SET NOT NULL,
+	ALTER COLUMN video_id DROP DEFAULT;
+
+ALTER TABLE tracks DROP CONSTRAINT tracks_pkey;
+ALTER TABLE tracks ADD PRIMARY KEY (track_id);
+ALTER TABLE tracks ADD CONSTRAINT tracks_track_key_key UNIQUE (track_key);
+
+COMMIT;
@@ -0,0 +1,2 @@
+-- Tracks are streamed through /stream, presigned URLs are not stored anymore.
+ALTER TABLE tracks DROP COLUMN track_uri;
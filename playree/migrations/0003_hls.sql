@@ -0,0 +1 @@
+ALTER TABLE tracks ADD COLUMN hls_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- The size of existing tracks is unknown, they count as 0 bytes against the
+-- stored bytes quota.
+BEGIN;
+
+ALTER TABLE tracks ADD COLUMN size_bytes BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE usage (
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	period TEXT NOT NULL,
+	streamed_bytes BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, period)
+);
+
+COMMIT;
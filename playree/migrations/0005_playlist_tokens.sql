@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE playlist_tokens (
+	token TEXT NOT NULL PRIMARY KEY,
+	playlist_id TEXT NOT NULL REFERENCES playlists(playlist_id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_playlist_tokens_on_playlist_id ON playlist_tokens(playlist_id);
+
+COMMIT;
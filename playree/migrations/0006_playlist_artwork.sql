@@ -0,0 +1 @@
+ALTER TABLE playlists ADD COLUMN artwork_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE app_passwords (
+	id TEXT NOT NULL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TIMESTAMP
+);
+
+CREATE INDEX idx_app_passwords_on_user_id ON app_passwords(user_id);
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+CREATE TABLE playlist_shares (
+	playlist_id TEXT NOT NULL REFERENCES playlists(playlist_id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	role TEXT NOT NULL CHECK (role IN ('viewer', 'editor')),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (playlist_id, user_id)
+);
+
+CREATE INDEX idx_playlist_shares_on_user_id ON playlist_shares(user_id);
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- Existing tracks were added by the owner of their playlist.
+BEGIN;
+
+ALTER TABLE tracks ADD COLUMN added_by TEXT NOT NULL DEFAULT '';
+
+UPDATE tracks t SET added_by = p.user_id
+FROM playlists p
+WHERE p.playlist_id = t.playlist_id;
+
+CREATE TABLE jobs (
+	job_id TEXT NOT NULL PRIMARY KEY,
+	playlist_id TEXT NOT NULL,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	kind TEXT NOT NULL CHECK (kind IN ('create', 'add')),
+	status TEXT NOT NULL CHECK (status IN ('pending', 'done', 'failed')),
+	error TEXT NOT NULL DEFAULT '',
+	track_count INTEGER NOT NULL DEFAULT 0,
+	tracks TEXT NOT NULL DEFAULT '[]',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_jobs_on_playlist_id ON jobs(playlist_id);
+
+COMMIT;
@@ -0,0 +1,16 @@
+-- Generated columns are computed for the existing rows when they are added.
+BEGIN;
+
+ALTER TABLE playlists ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', playlist_name)) STORED;
+
+CREATE INDEX idx_playlists_on_search_vector ON playlists USING GIN (search_vector);
+
+ALTER TABLE tracks ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+	setweight(to_tsvector('simple', title), 'A') ||
+	setweight(to_tsvector('simple', artists), 'B') ||
+	setweight(to_tsvector('simple', album), 'C')
+) STORED;
+
+CREATE INDEX idx_tracks_on_search_vector ON tracks USING GIN (search_vector);
+
+COMMIT;
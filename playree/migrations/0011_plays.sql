@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE plays (
+	id BIGSERIAL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	track_id TEXT NOT NULL REFERENCES tracks(track_id) ON DELETE CASCADE,
+	playlist_id TEXT NOT NULL REFERENCES playlists(playlist_id) ON DELETE CASCADE,
+	event TEXT NOT NULL CHECK (event IN ('start', 'complete', 'skip')),
+	position_ms BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_plays_on_user_id_and_created_at ON plays(user_id, created_at);
+CREATE INDEX idx_plays_on_playlist_id ON plays(playlist_id);
+
+COMMIT;
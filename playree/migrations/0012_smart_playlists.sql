@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE smart_playlists (
+	smart_playlist_id TEXT NOT NULL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	rule TEXT NOT NULL,
+	track_limit INTEGER NOT NULL DEFAULT 50,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_smart_playlists_on_user_id ON smart_playlists(user_id);
+
+COMMIT;
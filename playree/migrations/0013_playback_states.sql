@@ -0,0 +1,10 @@
+CREATE TABLE playback_states (
+	user_id TEXT NOT NULL PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+	source TEXT NOT NULL CHECK (source IN ('playlist', 'smart')),
+	playlist_id TEXT NOT NULL,
+	track_id TEXT NOT NULL,
+	track_index INTEGER NOT NULL DEFAULT 0,
+	position_ms BIGINT NOT NULL DEFAULT 0,
+	device TEXT NOT NULL DEFAULT '',
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
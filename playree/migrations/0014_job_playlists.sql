@@ -0,0 +1,4 @@
+-- Create jobs carry the playlist they insert once they are done.
+ALTER TABLE jobs
+	ADD COLUMN playlist_name TEXT NOT NULL DEFAULT '',
+	ADD COLUMN artwork_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+-- Playlists imported before this cannot be synced, their Spotify playlist
+-- was not recorded.
+BEGIN;
+
+ALTER TABLE playlists ADD COLUMN spotify_playlist_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE jobs ADD COLUMN spotify_playlist_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE jobs DROP CONSTRAINT jobs_kind_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_kind_check CHECK (kind IN ('create', 'add', 'sync'));
+
+COMMIT;
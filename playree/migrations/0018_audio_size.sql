@@ -0,0 +1,3 @@
+-- size_bytes includes HLS renditions, enclosures need the size of the audio
+-- object alone. Tracks stored before this get it on first use.
+ALTER TABLE tracks ADD COLUMN audio_size_bytes BIGINT NOT NULL DEFAULT 0;
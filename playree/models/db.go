@@ -3,14 +3,18 @@ package models
 import "time"
 
 type UserDBModel struct {
-	UserID   string `gorm:"column:user_id;primaryKey"`
-	Username string `gorm:"column:username"`
+	UserID   string `gorm:"column:user_id;primaryKey" json:"user_id"`
+	Username string `gorm:"column:username" json:"username"`
 }
 
 type PlaylistsDBModel struct {
-	UserID       string `gorm:"column:user_id"`
-	PlaylistID   string `gorm:"column:playlist_id"`
-	PlaylistName string `gorm:"column:playlist_name"`
+	UserID       string `gorm:"column:user_id" json:"user_id"`
+	PlaylistID   string `gorm:"column:playlist_id" json:"playlist_id"`
+	PlaylistName string `gorm:"column:playlist_name" json:"playlist_name"`
+	ArtworkURL   string `gorm:"column:artwork_url" json:"artwork_url,omitempty"`
+	// SpotifyPlaylistID is the imported Spotify playlist, syncs add its new
+	// tracks.
+	SpotifyPlaylistID string `gorm:"column:spotify_playlist_id" json:"spotify_playlist_id,omitempty"`
 }
 
 // type TrackDBModel struct {
@@ -21,8 +25,181 @@ type PlaylistsDBModel struct {
 // }
 
 type TrackDBModel struct {
-	PlaylistID string    `gorm:"column:playlist_id" json:"playlist_id,omitempty"`
-	TrackKey   string    `gorm:"column:track_key;primaryKey" json:"track_key,omitempty"`
-	TrackURI   string    `gorm:"column:track_uri"  json:"track_uri,omitempty"`
-	InsertedAt time.Time `gorm:"column:inserted_at;default:CURRENT_TIMESTAMP" json:"inserted_at,omitempty"`
+	TrackID        string    `gorm:"column:track_id;primaryKey" json:"track_id,omitempty"`
+	PlaylistID     string    `gorm:"column:playlist_id" json:"playlist_id,omitempty"`
+	TrackKey       string    `gorm:"column:track_key" json:"-"`
+	Title          string    `gorm:"column:title" json:"title,omitempty"`
+	Artists        string    `gorm:"column:artists" json:"artists,omitempty"`
+	Album          string    `gorm:"column:album" json:"album,omitempty"`
+	DurationMs     int64     `gorm:"column:duration_ms" json:"duration_ms,omitempty"`
+	Position       int       `gorm:"column:position" json:"position"`
+	VideoID        string    `gorm:"column:video_id" json:"video_id,omitempty"`
+	HLSKey         string    `gorm:"column:hls_key" json:"-"`
+	SpotifyTrackID string    `gorm:"column:spotify_track_id" json:"spotify_track_id,omitempty"`
+	SizeBytes      int64     `gorm:"column:size_bytes" json:"size_bytes,omitempty"`
+	AudioSizeBytes int64     `gorm:"column:audio_size_bytes" json:"audio_size_bytes,omitempty"`
+	AddedBy        string    `gorm:"column:added_by" json:"added_by,omitempty"`
+	InsertedAt     time.Time `gorm:"column:inserted_at;default:CURRENT_TIMESTAMP" json:"inserted_at,omitempty"`
+
+	// HasHLS is only read, by queries that select hls_key <> '' AS has_hls.
+	HasHLS bool `gorm:"column:has_hls;->;-:migration" json:"has_hls,omitempty"`
+}
+
+type UsageDBModel struct {
+	UserID        string `gorm:"column:user_id;primaryKey"`
+	Period        string `gorm:"column:period;primaryKey"`
+	StreamedBytes int64  `gorm:"column:streamed_bytes"`
+}
+
+type PlaylistTokenDBModel struct {
+	Token      string     `gorm:"column:token;primaryKey"`
+	PlaylistID string     `gorm:"column:playlist_id"`
+	UserID     string     `gorm:"column:user_id"`
+	Kind       string     `gorm:"column:kind"`
+	CreatedAt  time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+}
+
+type AppPasswordDBModel struct {
+	ID         string     `gorm:"column:id;primaryKey"`
+	UserID     string     `gorm:"column:user_id"`
+	Name       string     `gorm:"column:name"`
+	Secret     string     `gorm:"column:secret"`
+	CreatedAt  time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+}
+
+type AccessTokenDBModel struct {
+	ID         string     `gorm:"column:id;primaryKey"`
+	UserID     string     `gorm:"column:user_id"`
+	Name       string     `gorm:"column:name"`
+	TokenHash  string     `gorm:"column:token_hash"`
+	Scope      string     `gorm:"column:scope"`
+	CreatedAt  time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+}
+
+type PlaylistShareDBModel struct {
+	PlaylistID string    `gorm:"column:playlist_id;primaryKey"`
+	UserID     string    `gorm:"column:user_id;primaryKey"`
+	Role       string    `gorm:"column:role"`
+	CreatedAt  time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+}
+
+// JobDBModel is a create, add or sync job of playlist_creator. PlaylistName
+// and ArtworkURL are only set for create jobs, the playlist row is inserted
+// with them once the job is done.
+type JobDBModel struct {
+	JobID             string     `gorm:"column:job_id;primaryKey" json:"job_id"`
+	PlaylistID        string     `gorm:"column:playlist_id" json:"playlist_id"`
+	UserID            string     `gorm:"column:user_id" json:"user_id"`
+	Kind              string     `gorm:"column:kind" json:"kind"`
+	Status            string     `gorm:"column:status" json:"status"`
+	Error             string     `gorm:"column:error" json:"error,omitempty"`
+	PlaylistName      string     `gorm:"column:playlist_name" json:"playlist_name,omitempty"`
+	ArtworkURL        string     `gorm:"column:artwork_url" json:"-"`
+	SpotifyPlaylistID string     `gorm:"column:spotify_playlist_id" json:"spotify_playlist_id,omitempty"`
+	TrackCount        int        `gorm:"column:track_count" json:"track_count"`
+	Tracks            string     `gorm:"column:tracks" json:"-"`
+	CreatedAt         time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	FinishedAt        *time.Time `gorm:"column:finished_at" json:"finished_at,omitempty"`
+}
+
+type PlayDBModel struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID     string    `gorm:"column:user_id"`
+	TrackID    string    `gorm:"column:track_id"`
+	PlaylistID string    `gorm:"column:playlist_id"`
+	Event      string    `gorm:"column:event"`
+	PositionMs int64     `gorm:"column:position_ms"`
+	CreatedAt  time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+}
+
+type SmartPlaylistDBModel struct {
+	SmartPlaylistID string    `gorm:"column:smart_playlist_id;primaryKey" json:"smart_playlist_id"`
+	UserID          string    `gorm:"column:user_id" json:"user_id"`
+	Name            string    `gorm:"column:name" json:"name"`
+	Rule            string    `gorm:"column:rule" json:"rule"`
+	TrackLimit      int       `gorm:"column:track_limit" json:"track_limit"`
+	CreatedAt       time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Smart playlist rule kinds.
+const (
+	SmartMostPlayed      = "most_played"
+	SmartRecentlyAdded   = "recently_added"
+	SmartByArtist        = "by_artist"
+	SmartNeverPlayed     = "never_played"
+	SmartLongestUnplayed = "longest_unplayed"
+)
+
+// SmartRule is the definition of a smart playlist, stored as JSON. Days is
+// the window of most_played and recently_added, Artist the name by_artist
+// matches.
+type SmartRule struct {
+	Kind   string `json:"kind"`
+	Days   int    `json:"days,omitempty"`
+	Artist string `json:"artist,omitempty"`
+}
+
+// PlaybackStateDBModel is where a user last was in a playlist, kept up to
+// date by the player's heartbeat. Source is "playlist" or "smart" and tells
+// which kind of playlist PlaylistID names.
+type PlaybackStateDBModel struct {
+	UserID     string    `gorm:"column:user_id;primaryKey" json:"-"`
+	Source     string    `gorm:"column:source" json:"source"`
+	PlaylistID string    `gorm:"column:playlist_id" json:"playlist_id"`
+	TrackID    string    `gorm:"column:track_id" json:"track_id"`
+	TrackIndex int       `gorm:"column:track_index" json:"track_index"`
+	PositionMs int64     `gorm:"column:position_ms" json:"position_ms"`
+	Device     string    `gorm:"column:device" json:"device"`
+	UpdatedAt  time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// PlayedTrack is a track from a user's listening history.
+type PlayedTrack struct {
+	TrackDBModel
+	PlaylistName string    `gorm:"column:playlist_name" json:"playlist_name"`
+	Plays        int64     `gorm:"column:plays" json:"plays"`
+	LastPlayedAt time.Time `gorm:"column:last_played_at" json:"last_played_at"`
+}
+
+// TrackPlayCount counts the play events of a track over all listeners.
+type TrackPlayCount struct {
+	TrackID     string `gorm:"column:track_id" json:"track_id"`
+	Plays       int64  `gorm:"column:plays" json:"plays"`
+	Completions int64  `gorm:"column:completions" json:"completions"`
+	Skips       int64  `gorm:"column:skips" json:"skips"`
+}
+
+// TrackSearchResult is a track found by full-text search, with the playlist
+// it belongs to.
+type TrackSearchResult struct {
+	TrackDBModel
+	PlaylistName string  `gorm:"column:playlist_name" json:"playlist_name"`
+	Rank         float64 `gorm:"column:rank" json:"rank"`
+}
+
+type WebhookDBModel struct {
+	ID        string    `gorm:"column:id;primaryKey"`
+	UserID    string    `gorm:"column:user_id"`
+	URL       string    `gorm:"column:url"`
+	Events    string    `gorm:"column:events"`
+	Secret    string    `gorm:"column:secret"`
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+}
+
+type WebhookDeliveryDBModel struct {
+	ID             string     `gorm:"column:id;primaryKey"`
+	WebhookID      string     `gorm:"column:webhook_id"`
+	Event          string     `gorm:"column:event"`
+	Payload        string     `gorm:"column:payload"`
+	Status         string     `gorm:"column:status"`
+	Attempts       int        `gorm:"column:attempts"`
+	ResponseStatus int        `gorm:"column:response_status"`
+	Error          string     `gorm:"column:error"`
+	CreatedAt      time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at"`
+	DeliveredAt    *time.Time `gorm:"column:delivered_at"`
 }
@@ -12,4 +12,32 @@ var (
 	ErrConfirmationTimeout            = errors.New("confirmation timeout")
 	ErrCreatePlaylistProcessNotExists = errors.New("no create playlist process running with playlist id: %s")
 	ErrCreatePlaylistServiceTimeout   = errors.New("create playlist service timeout")
+	ErrSmartPlaylistNotExists         = errors.New("smart playlist not exists")
+	ErrInvalidSmartRule               = errors.New("invalid smart playlist rule")
+	ErrPlaybackStateNotExists         = errors.New("playback state not exists")
+	ErrInvalidPlaybackSource          = errors.New("invalid playback source (playlist or smart)")
+	ErrInvalidPlayEvent               = errors.New("invalid play event (start, complete or skip)")
+	ErrJobNotExists                   = errors.New("job not exists")
+	ErrNoTracksToAdd                  = errors.New("no tracks to add")
+	ErrTooManyTracksToAdd             = errors.New("too many tracks to add (at most 50 can be added at once)")
+	ErrInvalidSpotifyLink             = errors.New("invalid spotify link (only track, album and playlist links are supported)")
+	ErrTrackNotExists                 = errors.New("track not exists")
+	ErrPlaylistNotExists              = errors.New("playlist not exists")
+	ErrPlaylistAccessDenied           = errors.New("you do not have access to this playlist")
+	ErrHLSNotExists                   = errors.New("no hls stream for this track")
+	ErrTrackQuotaExceeded             = errors.New("track quota exceeded")
+	ErrStorageQuotaExceeded           = errors.New("storage quota exceeded")
+	ErrInvalidTrackOrder              = errors.New("track order has to list every track of the playlist once")
+	ErrInvalidRole                    = errors.New("invalid role (only 'viewer' and 'editor' are allowed)")
+	ErrCannotShareWithOwner           = errors.New("the owner already has access to this playlist")
+	ErrInvalidPlaylistToken           = errors.New("invalid or revoked playlist token")
+	ErrBandwidthQuotaExceeded         = errors.New("monthly streaming quota exceeded")
+	ErrAuthenticationRequired         = errors.New("authentication required")
+	ErrInvalidAccessToken             = errors.New("invalid or revoked access token")
+	ErrInvalidScope                   = errors.New("invalid scope (only 'read' and 'read-write' are allowed)")
+	ErrInsufficientScope              = errors.New("this access token is read-only")
+	ErrPlaylistNotSyncable            = errors.New("playlist has no spotify playlist to sync with, import it again to sync it")
+	ErrWebhookNotExists               = errors.New("webhook not exists")
+	ErrInvalidWebhookURL              = errors.New("invalid webhook url (only http and https urls of public hosts are allowed)")
+	ErrInvalidWebhookEvents           = errors.New("invalid webhook events")
 )
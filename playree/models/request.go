@@ -1,11 +1,15 @@
 package models
 
 type Track struct {
-	Name    string `json:"name,omitempty"`
-	Artists string `json:"artists,omitempty"`
+	Name           string `json:"name,omitempty"`
+	Artists        string `json:"artists,omitempty"`
+	Album          string `json:"album,omitempty"`
+	DurationMs     int64  `json:"duration_ms,omitempty"`
+	SpotifyTrackID string `json:"spotify_track_id,omitempty"`
 }
 
 type CreatePlaylistRequest struct {
+	JobID             string   `json:"job_id,omitempty"`
 	PlayreePlaylistID string   `json:"playree_playlist_id,omitempty"`
 	Tracks            []*Track `json:"tracks,omitempty"`
 }
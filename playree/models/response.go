@@ -1,8 +1,31 @@
 package models
 
+type CreatedTrack struct {
+	Position       int    `json:"position,omitempty"`
+	TrackID        string `json:"track_id,omitempty"`
+	TrackKey       string `json:"track_key,omitempty"`
+	VideoID        string `json:"video_id,omitempty"`
+	HLSKey         string `json:"hls_key,omitempty"`
+	SizeBytes      int64  `json:"size_bytes,omitempty"`
+	AudioSizeBytes int64  `json:"audio_size_bytes,omitempty"`
+}
+
 type RabbitMQCreatePlaylistResponse struct {
-	PlayreePlaylistID string `json:"playree_playlist_id,omitempty"`
-	PlaylistName      string `json:"playlist_name,omitempty"`
-	Success           bool   `json:"success,omitempty"`
-	Error             string `json:"error,omitempty"`
+	JobID             string         `json:"job_id,omitempty"`
+	PlayreePlaylistID string         `json:"playree_playlist_id,omitempty"`
+	PlaylistName      string         `json:"playlist_name,omitempty"`
+	Tracks            []CreatedTrack `json:"tracks,omitempty"`
+	Success           bool           `json:"success,omitempty"`
+	Error             string         `json:"error,omitempty"`
+}
+
+// Usage is what a user has stored and streamed, next to the configured limits.
+// A limit of 0 means unlimited.
+type Usage struct {
+	Tracks           int64 `json:"tracks"`
+	StoredBytes      int64 `json:"stored_bytes"`
+	StreamedBytes    int64 `json:"streamed_bytes"`
+	MaxTracks        int64 `json:"max_tracks"`
+	MaxStoredBytes   int64 `json:"max_stored_bytes"`
+	MaxStreamedBytes int64 `json:"max_streamed_bytes"`
 }
@@ -0,0 +1,256 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v5.26.1
+// source: proto/playree.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PlayreeService_ListPlaylists_FullMethodName  = "/PlayreeService/ListPlaylists"
+	PlayreeService_GetPlaylist_FullMethodName    = "/PlayreeService/GetPlaylist"
+	PlayreeService_CreatePlaylist_FullMethodName = "/PlayreeService/CreatePlaylist"
+	PlayreeService_WatchJob_FullMethodName       = "/PlayreeService/WatchJob"
+)
+
+// PlayreeServiceClient is the client API for PlayreeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PlayreeServiceClient interface {
+	ListPlaylists(ctx context.Context, in *ListPlaylistsRequest, opts ...grpc.CallOption) (*ListPlaylistsResponse, error)
+	GetPlaylist(ctx context.Context, in *GetPlaylistRequest, opts ...grpc.CallOption) (*GetPlaylistResponse, error)
+	// CreatePlaylist starts importing a Spotify playlist, the playlist exists
+	// once the returned job is done.
+	CreatePlaylist(ctx context.Context, in *CreatePlaylistRequest, opts ...grpc.CallOption) (*Job, error)
+	// WatchJob sends the current state of a job and every change after it,
+	// the stream ends once the job is done or failed.
+	WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (PlayreeService_WatchJobClient, error)
+}
+
+type playreeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPlayreeServiceClient(cc grpc.ClientConnInterface) PlayreeServiceClient {
+	return &playreeServiceClient{cc}
+}
+
+func (c *playreeServiceClient) ListPlaylists(ctx context.Context, in *ListPlaylistsRequest, opts ...grpc.CallOption) (*ListPlaylistsResponse, error) {
+	out := new(ListPlaylistsResponse)
+	err := c.cc.Invoke(ctx, PlayreeService_ListPlaylists_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playreeServiceClient) GetPlaylist(ctx context.Context, in *GetPlaylistRequest, opts ...grpc.CallOption) (*GetPlaylistResponse, error) {
+	out := new(GetPlaylistResponse)
+	err := c.cc.Invoke(ctx, PlayreeService_GetPlaylist_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playreeServiceClient) CreatePlaylist(ctx context.Context, in *CreatePlaylistRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, PlayreeService_CreatePlaylist_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playreeServiceClient) WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (PlayreeService_WatchJobClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PlayreeService_ServiceDesc.Streams[0], PlayreeService_WatchJob_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &playreeServiceWatchJobClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PlayreeService_WatchJobClient interface {
+	Recv() (*Job, error)
+	grpc.ClientStream
+}
+
+type playreeServiceWatchJobClient struct {
+	grpc.ClientStream
+}
+
+func (x *playreeServiceWatchJobClient) Recv() (*Job, error) {
+	m := new(Job)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PlayreeServiceServer is the server API for PlayreeService service.
+// All implementations must embed UnimplementedPlayreeServiceServer
+// for forward compatibility
+type PlayreeServiceServer interface {
+	ListPlaylists(context.Context, *ListPlaylistsRequest) (*ListPlaylistsResponse, error)
+	GetPlaylist(context.Context, *GetPlaylistRequest) (*GetPlaylistResponse, error)
+	// CreatePlaylist starts importing a Spotify playlist, the playlist exists
+	// once the returned job is done.
+	CreatePlaylist(context.Context, *CreatePlaylistRequest) (*Job, error)
+	// WatchJob sends the current state of a job and every change after it,
+	// the stream ends once the job is done or failed.
+	WatchJob(*WatchJobRequest, PlayreeService_WatchJobServer) error
+	mustEmbedUnimplementedPlayreeServiceServer()
+}
+
+// UnimplementedPlayreeServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPlayreeServiceServer struct {
+}
+
+func (UnimplementedPlayreeServiceServer) ListPlaylists(context.Context, *ListPlaylistsRequest) (*ListPlaylistsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPlaylists not implemented")
+}
+func (UnimplementedPlayreeServiceServer) GetPlaylist(context.Context, *GetPlaylistRequest) (*GetPlaylistResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPlaylist not implemented")
+}
+func (UnimplementedPlayreeServiceServer) CreatePlaylist(context.Context, *CreatePlaylistRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePlaylist not implemented")
+}
+func (UnimplementedPlayreeServiceServer) WatchJob(*WatchJobRequest, PlayreeService_WatchJobServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchJob not implemented")
+}
+func (UnimplementedPlayreeServiceServer) mustEmbedUnimplementedPlayreeServiceServer() {}
+
+// UnsafePlayreeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PlayreeServiceServer will
+// result in compilation errors.
+type UnsafePlayreeServiceServer interface {
+	mustEmbedUnimplementedPlayreeServiceServer()
+}
+
+func RegisterPlayreeServiceServer(s grpc.ServiceRegistrar, srv PlayreeServiceServer) {
+	s.RegisterService(&PlayreeService_ServiceDesc, srv)
+}
+
+func _PlayreeService_ListPlaylists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPlaylistsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayreeServiceServer).ListPlaylists(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayreeService_ListPlaylists_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayreeServiceServer).ListPlaylists(ctx, req.(*ListPlaylistsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayreeService_GetPlaylist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPlaylistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayreeServiceServer).GetPlaylist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayreeService_GetPlaylist_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayreeServiceServer).GetPlaylist(ctx, req.(*GetPlaylistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayreeService_CreatePlaylist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePlaylistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayreeServiceServer).CreatePlaylist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayreeService_CreatePlaylist_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayreeServiceServer).CreatePlaylist(ctx, req.(*CreatePlaylistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayreeService_WatchJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PlayreeServiceServer).WatchJob(m, &playreeServiceWatchJobServer{stream})
+}
+
+type PlayreeService_WatchJobServer interface {
+	Send(*Job) error
+	grpc.ServerStream
+}
+
+type playreeServiceWatchJobServer struct {
+	grpc.ServerStream
+}
+
+func (x *playreeServiceWatchJobServer) Send(m *Job) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// PlayreeService_ServiceDesc is the grpc.ServiceDesc for PlayreeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PlayreeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "PlayreeService",
+	HandlerType: (*PlayreeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPlaylists",
+			Handler:    _PlayreeService_ListPlaylists_Handler,
+		},
+		{
+			MethodName: "GetPlaylist",
+			Handler:    _PlayreeService_GetPlaylist_Handler,
+		},
+		{
+			MethodName: "CreatePlaylist",
+			Handler:    _PlayreeService_CreatePlaylist_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchJob",
+			Handler:       _PlayreeService_WatchJob_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/playree.proto",
+}
@@ -0,0 +1,45 @@
+window.addEventListener("DOMContentLoaded", (_) => {
+  let container = document.querySelector(".manage-container");
+  let list = document.getElementById("tracks");
+  let errorMessageElement = document.getElementById("error-message");
+  let dragged = null;
+
+  list.addEventListener("dragstart", (e) => {
+    dragged = e.target.closest("li");
+    dragged.classList.add("dragging");
+  });
+
+  list.addEventListener("dragover", (e) => {
+    e.preventDefault();
+
+    let target = e.target.closest("li");
+    if (!target || target === dragged) {
+      return;
+    }
+
+    let rect = target.getBoundingClientRect();
+    if (e.clientY > rect.top + rect.height / 2) {
+      target.after(dragged);
+    } else {
+      target.before(dragged);
+    }
+  });
+
+  list.addEventListener("dragend", (_) => {
+    dragged.classList.remove("dragging");
+    dragged = null;
+    saveOrder();
+  });
+
+  const saveOrder = () => {
+    let trackIDs = Array.from(list.querySelectorAll("li")).map((li) => li.dataset.trackId);
+
+    fetch("/playlist/" + container.dataset.playlistId + "/reorder", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ track_ids: trackIDs }),
+    }).then((resp) => {
+      errorMessageElement.textContent = resp.ok ? "" : "Error: failed to save the new order. Reload and try again.";
+    });
+  };
+});
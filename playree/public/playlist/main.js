@@ -9,30 +9,49 @@ let track_list = [
 const TIMEOUT_DURATION = 10000;
 
 window.addEventListener("DOMContentLoaded", (_) => {
-	let websocket = new WebSocket("ws://" + window.location.host + "/send-playlist-data");
   let timeoutId;
   let errorMessageElement = document.getElementById("error-message");
   const loadFirstTrackEvent = new Event('load-first-track');
-  
-	websocket.addEventListener("message", function (e) {
-      const tracks = JSON.parse(e.data);
 
+  const setTracks = (tracks) => {
       let index = 0;
 
       tracks.forEach(track => {
         track_list[index] = {
-          name : getSongName(track.track_key),
-          artist : getArtists(track.track_key),
-          path : track.track_uri, 
+          track_id : track.track_id,
+          name : track.title,
+          artist : track.artists,
+          path : "/stream/" + track.track_id,
+          hls : track.has_hls ? "/stream/" + track.track_id + "/hls/master.m3u8" : "",
         };
         index++;
       });
 
        document.dispatchEvent(loadFirstTrackEvent);
-       websocket.close();
-       websocket.removeEventListener("close ws connection", this);
        clearTimeout(timeoutId);
-	});
+  };
+
+  // anonymous share links and smart playlists only get the player
+  if (window.location.pathname.startsWith("/s/") || window.location.pathname.startsWith("/smart/")) {
+    ["download", "exports", "manage"].forEach(id => document.getElementById(id).remove());
+  } else {
+    document.getElementById("download").href = window.location.pathname + "/download";
+    document.getElementById("exports").href = window.location.pathname + "/exports";
+    document.getElementById("manage").href = window.location.pathname + "/manage";
+  }
+
+  // smart playlists are materialised on request, their tracks come as JSON
+  if (window.location.pathname.startsWith("/smart/")) {
+    fetch(window.location.pathname + "/tracks")
+      .then(resp => resp.json())
+      .then(setTracks)
+      .catch(err => console.error("Failed to load tracks:", err));
+  } else {
+    playlistID()
+      .then(id => fetchAllTracks("/api/v1/playlists/" + id + "/tracks"))
+      .then(setTracks)
+      .catch(err => console.error("Failed to load tracks:", err));
+  }
 
   const handleTimeout = () => {
     console.error("Failed to receive track data within", TIMEOUT_DURATION / 1000, "seconds.");
@@ -40,44 +59,54 @@ window.addEventListener("DOMContentLoaded", (_) => {
   };
 
   document.addEventListener('load-first-track', function() {
-    loadTrack(0); 
+    // search results link to /playlist/<id>?track=<track id>, resume links
+    // add the position in seconds as t
+    const params = new URLSearchParams(window.location.search);
+    const index = track_list.findIndex(track => track.track_id === params.get("track"));
+    if (index >= 0) track_index = index;
+
+    loadTrack(track_index);
+
+    const start = parseInt(params.get("t"), 10);
+    if (index >= 0 && start > 0) {
+      curr_track.addEventListener("loadedmetadata", () => { curr_track.currentTime = start; }, { once: true });
+    } else if (index < 0) {
+      offerResume();
+    }
   });
 
   timeoutId = setTimeout(handleTimeout, TIMEOUT_DURATION);
 });
 
 
-function getSongName(str) {
-  const firstIndex = str.indexOf("_"); 
-  if (firstIndex === -1) {
-    return str; 
-  }
-
-  const secondIndex = str.indexOf("_", firstIndex + 1); 
+// playlistID resolves the playlist of the page, share links look it up with
+// their token
+function playlistID() {
+  const parts = window.location.pathname.split("/");
+  if (parts[1] !== "s") return Promise.resolve(parts[2]);
 
-  if (secondIndex === -1) {
-    return str.substring(0, firstIndex); 
-  }
-
-  return str.substring(firstIndex + 1, secondIndex);
+  return fetch("/api/v1/shares/" + parts[2])
+    .then(resp => resp.json())
+    .then(playlist => playlist.playlist_id);
 }
 
-function getArtists(str) {
-  const firstIndex = str.indexOf("$");  
-  if (firstIndex === -1) {
-    return str;  
-  }
+// fetchAllTracks follows the pages of the tracks API
+async function fetchAllTracks(url) {
+  let tracks = [];
+  let offset = 0;
 
-  const secondIndex = str.indexOf("$", firstIndex + 1); 
+  while (offset !== null) {
+    const resp = await fetch(url + "?limit=500&offset=" + offset);
+    if (!resp.ok) throw new Error("tracks request failed with " + resp.status);
 
-  if (secondIndex === -1) {
-    return str.substring(0, firstIndex);  
+    const page = await resp.json();
+    tracks = tracks.concat(page.items);
+    offset = page.next_offset;
   }
 
-  return str.substring(firstIndex+1, secondIndex)
+  return tracks;
 }
 
-
 let now_playing = document.querySelector(".now-playing");
 let track_art = document.querySelector(".track-art");
 let track_name = document.querySelector(".track-name");
@@ -99,15 +128,104 @@ let updateTimer;
 
 // Create the audio element for the player
 let curr_track = document.createElement('audio');
+let hls = null;
+
+// Listening history: a play is reported when a loaded track starts playing,
+// and once more when it completes or is skipped
+let play_reported = false;
+
+function reportPlay(event) {
+  const track = track_list[track_index];
+  if (!track.track_id) return;
+
+  fetch("/tracks/" + track.track_id + "/plays", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ event: event, position_ms: Math.floor(curr_track.currentTime * 1000) }),
+    keepalive: true,
+  }).catch(err => console.error("Failed to report play:", err));
+}
+
+curr_track.addEventListener("playing", () => {
+  if (play_reported) return;
+  play_reported = true;
+  reportPlay("start");
+});
+
+curr_track.addEventListener("ended", () => reportPlay("complete"));
+
+// Playback sync: the player saves where it is every few seconds while playing,
+// so another device can resume there
+const HEARTBEAT_INTERVAL = 15000;
+
+function playbackSource() {
+  const parts = window.location.pathname.split("/");
+  if (parts[1] === "playlist") return { source: "playlist", playlist_id: parts[2] };
+  if (parts[1] === "smart") return { source: "smart", playlist_id: parts[2] };
+  // anonymous share links have no account to sync to
+  return null;
+}
+
+function savePlayback() {
+  const source = playbackSource();
+  const track = track_list[track_index];
+  if (!source || !track.track_id) return;
+
+  fetch("/playback", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({
+      source: source.source,
+      playlist_id: source.playlist_id,
+      track_id: track.track_id,
+      track_index: track_index,
+      position_ms: Math.floor(curr_track.currentTime * 1000),
+      device: navigator.platform,
+    }),
+    keepalive: true,
+  }).catch(err => console.error("Failed to save playback:", err));
+}
+
+setInterval(() => { if (isPlaying) savePlayback(); }, HEARTBEAT_INTERVAL);
+curr_track.addEventListener("pause", savePlayback);
+
+// offerResume shows a resume button when the user left off somewhere in
+// this playlist, possibly on another device
+function offerResume() {
+  const source = playbackSource();
+  if (!source) return;
+
+  fetch("/playback")
+    .then(resp => resp.ok ? resp.json() : null)
+    .then(state => {
+      if (!state || state.source !== source.source || state.playlist_id !== source.playlist_id) return;
+
+      const index = track_list.findIndex(track => track.track_id === state.track_id);
+      if (index < 0) return;
+
+      const button = document.getElementById("resume");
+      button.textContent = "Resume " + state.title + " at " + Math.floor(state.position_ms / 60000) + ":" +
+        String(Math.floor(state.position_ms / 1000) % 60).padStart(2, "0");
+      button.hidden = false;
+      button.onclick = () => {
+        button.hidden = true;
+        track_index = index;
+        loadTrack(track_index);
+        curr_track.addEventListener("loadedmetadata", () => { curr_track.currentTime = state.position_ms / 1000; }, { once: true });
+        playTrack();
+      };
+    })
+    .catch(err => console.error("Failed to load playback state:", err));
+}
 
 function loadTrack(track_index) {
   // Clear the previous seek timer
   clearInterval(updateTimer);
+  play_reported = false;
   resetValues();
   
   // Load a new track
-  curr_track.src = track_list[track_index].path;
-  curr_track.load();
+  loadSource(track_list[track_index]);
   
  
   track_name.textContent = track_list[track_index].name;
@@ -127,6 +245,29 @@ function loadTrack(track_index) {
   random_bg_color();
   }
   
+  function loadSource(track) {
+  // Prefer the adaptive HLS stream when the track has one,
+  // either through hls.js or the browser's native support
+  if (hls) {
+    hls.destroy();
+    hls = null;
+  }
+
+  if (track.hls && window.Hls && Hls.isSupported()) {
+    hls = new Hls();
+    hls.loadSource(track.hls);
+    hls.attachMedia(curr_track);
+    return;
+  }
+
+  if (track.hls && curr_track.canPlayType("application/vnd.apple.mpegurl")) {
+    curr_track.src = track.hls;
+  } else {
+    curr_track.src = track.path;
+  }
+  curr_track.load();
+  }
+
   function random_bg_color() {
   // Get a random number between 64 to 256
   // (for getting lighter colors)
@@ -174,6 +315,8 @@ function loadTrack(track_index) {
     }
     
     function nextTrack() {
+    if (play_reported && !curr_track.ended) reportPlay("skip");
+
     // Go back to the first track if the
     // current one is the last in the track list
     if (track_index < track_list.length - 1)
@@ -186,6 +329,8 @@ function loadTrack(track_index) {
     }
     
     function prevTrack() {
+    if (play_reported && !curr_track.ended) reportPlay("skip");
+
     // Go back to the last track if the
     // current one is the first in the track list
     if (track_index > 0)
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+)
+
+type AccessTokenStore interface {
+	CreateTable() error
+	Create(token models.AccessTokenDBModel) error
+	GetOne(whereQuery string, whereArgs ...interface{}) (*models.AccessTokenDBModel, error)
+	GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.AccessTokenDBModel, error)
+	Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error
+	Delete(whereQuery string, whereArgs ...interface{}) error
+	DB() *gorm.DB
+}
+
+type accessTokenStore struct {
+	db *gorm.DB
+}
+
+func NewAccessTokenStore(db *gorm.DB) AccessTokenStore {
+	return &accessTokenStore{
+		db: db,
+	}
+}
+
+func (ts *accessTokenStore) table() string {
+	return "access_tokens"
+}
+
+func (ts *accessTokenStore) DB() *gorm.DB {
+	return ts.db
+}
+
+func (ts *accessTokenStore) CreateTable() error {
+	return ts.db.Table(ts.table()).AutoMigrate(models.AccessTokenDBModel{})
+}
+
+func (ts *accessTokenStore) Create(token models.AccessTokenDBModel) error {
+	return ts.db.Table(ts.table()).Create(token).Error
+}
+
+func (ts *accessTokenStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.AccessTokenDBModel, error) {
+	var token models.AccessTokenDBModel
+	if err := ts.db.Table(ts.table()).Where(whereQuery, whereArgs...).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (ts *accessTokenStore) GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.AccessTokenDBModel, error) {
+	var tokens []models.AccessTokenDBModel
+
+	if err := ts.db.Table(ts.table()).Where(whereQuery, whereArgs...).Order(order).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (ts *accessTokenStore) Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error {
+	return ts.db.Table(ts.table()).Where(whereQuery, whereArgs...).Updates(updateMap).Error
+}
+
+func (ts *accessTokenStore) Delete(whereQuery string, whereArgs ...interface{}) error {
+	return ts.db.Table(ts.table()).Where(whereQuery, whereArgs...).Delete(nil).Error
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+)
+
+type AppPasswordStore interface {
+	CreateTable() error
+	Create(password models.AppPasswordDBModel) error
+	GetOne(whereQuery string, whereArgs ...interface{}) (*models.AppPasswordDBModel, error)
+	GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.AppPasswordDBModel, error)
+	Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error
+	Delete(whereQuery string, whereArgs ...interface{}) error
+	DB() *gorm.DB
+}
+
+type appPasswordStore struct {
+	db *gorm.DB
+}
+
+func NewAppPasswordStore(db *gorm.DB) AppPasswordStore {
+	return &appPasswordStore{
+		db: db,
+	}
+}
+
+func (as *appPasswordStore) table() string {
+	return "app_passwords"
+}
+
+func (as *appPasswordStore) DB() *gorm.DB {
+	return as.db
+}
+
+func (as *appPasswordStore) CreateTable() error {
+	return as.db.Table(as.table()).AutoMigrate(models.AppPasswordDBModel{})
+}
+
+func (as *appPasswordStore) Create(password models.AppPasswordDBModel) error {
+	return as.db.Table(as.table()).Create(password).Error
+}
+
+func (as *appPasswordStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.AppPasswordDBModel, error) {
+	var password models.AppPasswordDBModel
+	if err := as.db.Table(as.table()).Where(whereQuery, whereArgs...).First(&password).Error; err != nil {
+		return nil, err
+	}
+
+	return &password, nil
+}
+
+func (as *appPasswordStore) GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.AppPasswordDBModel, error) {
+	var passwords []models.AppPasswordDBModel
+
+	if err := as.db.Table(as.table()).Where(whereQuery, whereArgs...).Order(order).Find(&passwords).Error; err != nil {
+		return nil, err
+	}
+
+	return passwords, nil
+}
+
+func (as *appPasswordStore) Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error {
+	return as.db.Table(as.table()).Where(whereQuery, whereArgs...).Updates(updateMap).Error
+}
+
+func (as *appPasswordStore) Delete(whereQuery string, whereArgs ...interface{}) error {
+	return as.db.Table(as.table()).Where(whereQuery, whereArgs...).Delete(nil).Error
+}
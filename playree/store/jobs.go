@@ -0,0 +1,76 @@
+package store
+
+import (
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+)
+
+type JobStore interface {
+	CreateTable() error
+	Create(job models.JobDBModel) error
+	GetOne(whereQuery string, whereArgs ...interface{}) (*models.JobDBModel, error)
+	GetPage(fields []string, order string, limit, offset int, whereQuery string, whereArgs ...interface{}) ([]models.JobDBModel, error)
+	Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error
+	UpdatePending(jobID string, updateMap map[string]any) (bool, error)
+	DB() *gorm.DB
+}
+
+type jobStore struct {
+	db *gorm.DB
+}
+
+func NewJobStore(db *gorm.DB) JobStore {
+	return &jobStore{
+		db: db,
+	}
+}
+
+func (js *jobStore) table() string {
+	return "jobs"
+}
+
+func (js *jobStore) DB() *gorm.DB {
+	return js.db
+}
+
+func (js *jobStore) CreateTable() error {
+	return js.db.Table(js.table()).AutoMigrate(models.JobDBModel{})
+}
+
+func (js *jobStore) Create(job models.JobDBModel) error {
+	return js.db.Table(js.table()).Create(job).Error
+}
+
+func (js *jobStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.JobDBModel, error) {
+	var job models.JobDBModel
+	if err := js.db.Table(js.table()).Where(whereQuery, whereArgs...).First(&job).Error; err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (js *jobStore) GetPage(fields []string, order string, limit, offset int, whereQuery string, whereArgs ...interface{}) ([]models.JobDBModel, error) {
+	var jobs []models.JobDBModel
+
+	if err := js.db.Table(js.table()).Select(fields).Where(whereQuery, whereArgs...).Order(order).Limit(limit).Offset(offset).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (js *jobStore) Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error {
+	return js.db.Table(js.table()).Where(whereQuery, whereArgs...).Updates(updateMap).Error
+}
+
+// UpdatePending updates a job only while it is pending and reports whether it
+// was, so a job is finished once even if its response is handled twice.
+func (js *jobStore) UpdatePending(jobID string, updateMap map[string]any) (bool, error) {
+	result := js.db.Table(js.table()).Where("job_id = ? AND status = 'pending'", jobID).Updates(updateMap)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
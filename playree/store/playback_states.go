@@ -0,0 +1,58 @@
+package store
+
+import (
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PlaybackStateStore interface {
+	CreateTable() error
+	GetOne(whereQuery string, whereArgs ...interface{}) (*models.PlaybackStateDBModel, error)
+	Save(state models.PlaybackStateDBModel) error
+	Delete(whereQuery string, whereArgs ...interface{}) error
+	DB() *gorm.DB
+}
+
+type playbackStateStore struct {
+	db *gorm.DB
+}
+
+func NewPlaybackStateStore(db *gorm.DB) PlaybackStateStore {
+	return &playbackStateStore{
+		db: db,
+	}
+}
+
+func (ps *playbackStateStore) table() string {
+	return "playback_states"
+}
+
+func (ps *playbackStateStore) DB() *gorm.DB {
+	return ps.db
+}
+
+func (ps *playbackStateStore) CreateTable() error {
+	return ps.db.Table(ps.table()).AutoMigrate(models.PlaybackStateDBModel{})
+}
+
+func (ps *playbackStateStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.PlaybackStateDBModel, error) {
+	var state models.PlaybackStateDBModel
+	if err := ps.db.Table(ps.table()).Where(whereQuery, whereArgs...).First(&state).Error; err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// Save replaces the playback state of the user, there is one per user.
+func (ps *playbackStateStore) Save(state models.PlaybackStateDBModel) error {
+	return ps.db.Table(ps.table()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(&state).Error
+}
+
+func (ps *playbackStateStore) Delete(whereQuery string, whereArgs ...interface{}) error {
+	return ps.db.Table(ps.table()).Where(whereQuery, whereArgs...).Delete(nil).Error
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PlaylistShareStore interface {
+	CreateTable() error
+	Upsert(share models.PlaylistShareDBModel) error
+	GetOne(whereQuery string, whereArgs ...interface{}) (*models.PlaylistShareDBModel, error)
+	GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.PlaylistShareDBModel, error)
+	Delete(whereQuery string, whereArgs ...interface{}) error
+	DB() *gorm.DB
+}
+
+type playlistShareStore struct {
+	db *gorm.DB
+}
+
+func NewPlaylistShareStore(db *gorm.DB) PlaylistShareStore {
+	return &playlistShareStore{
+		db: db,
+	}
+}
+
+func (ss *playlistShareStore) table() string {
+	return "playlist_shares"
+}
+
+func (ss *playlistShareStore) DB() *gorm.DB {
+	return ss.db
+}
+
+func (ss *playlistShareStore) CreateTable() error {
+	return ss.db.Table(ss.table()).AutoMigrate(models.PlaylistShareDBModel{})
+}
+
+// Upsert shares a playlist with a user, or changes the role of an existing
+// share.
+func (ss *playlistShareStore) Upsert(share models.PlaylistShareDBModel) error {
+	return ss.db.Table(ss.table()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "playlist_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(&share).Error
+}
+
+func (ss *playlistShareStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.PlaylistShareDBModel, error) {
+	var share models.PlaylistShareDBModel
+	if err := ss.db.Table(ss.table()).Where(whereQuery, whereArgs...).First(&share).Error; err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+func (ss *playlistShareStore) GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.PlaylistShareDBModel, error) {
+	var shares []models.PlaylistShareDBModel
+
+	if err := ss.db.Table(ss.table()).Where(whereQuery, whereArgs...).Order(order).Find(&shares).Error; err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func (ss *playlistShareStore) Delete(whereQuery string, whereArgs ...interface{}) error {
+	return ss.db.Table(ss.table()).Where(whereQuery, whereArgs...).Delete(nil).Error
+}
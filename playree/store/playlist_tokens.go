@@ -0,0 +1,69 @@
+package store
+
+import (
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+)
+
+type PlaylistTokenStore interface {
+	CreateTable() error
+	Create(token models.PlaylistTokenDBModel) error
+	GetOne(whereQuery string, whereArgs ...interface{}) (*models.PlaylistTokenDBModel, error)
+	GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.PlaylistTokenDBModel, error)
+	Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error
+	Delete(whereQuery string, whereArgs ...interface{}) error
+	DB() *gorm.DB
+}
+
+type playlistTokenStore struct {
+	db *gorm.DB
+}
+
+func NewPlaylistTokenStore(db *gorm.DB) PlaylistTokenStore {
+	return &playlistTokenStore{
+		db: db,
+	}
+}
+
+func (ts *playlistTokenStore) table() string {
+	return "playlist_tokens"
+}
+
+func (ts *playlistTokenStore) DB() *gorm.DB {
+	return ts.db
+}
+
+func (ts *playlistTokenStore) CreateTable() error {
+	return ts.db.Table(ts.table()).AutoMigrate(models.PlaylistTokenDBModel{})
+}
+
+func (ts *playlistTokenStore) Create(token models.PlaylistTokenDBModel) error {
+	return ts.db.Table(ts.table()).Create(token).Error
+}
+
+func (ts *playlistTokenStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.PlaylistTokenDBModel, error) {
+	var token models.PlaylistTokenDBModel
+	if err := ts.db.Table(ts.table()).Where(whereQuery, whereArgs...).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (ts *playlistTokenStore) GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.PlaylistTokenDBModel, error) {
+	var tokens []models.PlaylistTokenDBModel
+
+	if err := ts.db.Table(ts.table()).Where(whereQuery, whereArgs...).Order(order).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (ts *playlistTokenStore) Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error {
+	return ts.db.Table(ts.table()).Where(whereQuery, whereArgs...).Updates(updateMap).Error
+}
+
+func (ts *playlistTokenStore) Delete(whereQuery string, whereArgs ...interface{}) error {
+	return ts.db.Table(ts.table()).Where(whereQuery, whereArgs...).Delete(nil).Error
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/NikhilSharmaWe/playree/playree/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PlaylistStore interface {
@@ -15,6 +16,8 @@ type PlaylistStore interface {
 	Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error
 	Delete(whereQuery string, whereArgs ...interface{}) error
 	IsExists(whereQuery string, whereArgs ...interface{}) (bool, error)
+	GetPage(fields []string, order string, limit, offset int, whereQuery string, whereArgs ...interface{}) ([]models.PlaylistsDBModel, error)
+	LockOne(whereQuery string, whereArgs ...interface{}) (*models.PlaylistsDBModel, error)
 	DB() *gorm.DB
 }
 
@@ -90,3 +93,24 @@ func (ps *playlistStore) IsExists(whereQuery string, whereArgs ...interface{}) (
 
 	return res.IsExists, nil
 }
+
+// LockOne loads a playlist and locks its row until the surrounding
+// transaction ends.
+func (ps *playlistStore) LockOne(whereQuery string, whereArgs ...interface{}) (*models.PlaylistsDBModel, error) {
+	var playlist models.PlaylistsDBModel
+	if err := ps.db.Table(ps.table()).Clauses(clause.Locking{Strength: "UPDATE"}).Where(whereQuery, whereArgs...).First(&playlist).Error; err != nil {
+		return nil, err
+	}
+
+	return &playlist, nil
+}
+
+func (ps *playlistStore) GetPage(fields []string, order string, limit, offset int, whereQuery string, whereArgs ...interface{}) ([]models.PlaylistsDBModel, error) {
+	var playlists []models.PlaylistsDBModel
+
+	if err := ps.db.Table(ps.table()).Select(fields).Where(whereQuery, whereArgs...).Order(order).Limit(limit).Offset(offset).Find(&playlists).Error; err != nil {
+		return nil, err
+	}
+
+	return playlists, nil
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+)
+
+type PlayStore interface {
+	CreateTable() error
+	Create(play models.PlayDBModel) error
+	RecentlyPlayed(userID string, limit int) ([]models.PlayedTrack, error)
+	MostPlayed(userID string, since time.Time, limit int) ([]models.PlayedTrack, error)
+	TrackCounts(playlistID string) ([]models.TrackPlayCount, error)
+	DB() *gorm.DB
+}
+
+type playStore struct {
+	db *gorm.DB
+}
+
+func NewPlayStore(db *gorm.DB) PlayStore {
+	return &playStore{
+		db: db,
+	}
+}
+
+func (ps *playStore) table() string {
+	return "plays"
+}
+
+func (ps *playStore) DB() *gorm.DB {
+	return ps.db
+}
+
+func (ps *playStore) CreateTable() error {
+	return ps.db.Table(ps.table()).AutoMigrate(models.PlayDBModel{})
+}
+
+func (ps *playStore) Create(play models.PlayDBModel) error {
+	return ps.db.Table(ps.table()).Create(&play).Error
+}
+
+// playedTracks groups the plays a user started since a point in time by
+// track.
+func (ps *playStore) playedTracks(userID string, since time.Time) *gorm.DB {
+	return ps.db.Table(ps.table()).
+		Select("tracks.*, playlists.playlist_name, COUNT(*) AS plays, MAX(plays.created_at) AS last_played_at").
+		Joins("JOIN tracks ON tracks.track_id = plays.track_id").
+		Joins("JOIN playlists ON playlists.playlist_id = tracks.playlist_id").
+		Where("plays.user_id = ? AND plays.event = ? AND plays.created_at >= ?", userID, "start", since).
+		Group("tracks.track_id, playlists.playlist_id")
+}
+
+func (ps *playStore) RecentlyPlayed(userID string, limit int) ([]models.PlayedTrack, error) {
+	var tracks []models.PlayedTrack
+
+	if err := ps.playedTracks(userID, time.Time{}).Order("last_played_at DESC").Limit(limit).Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+
+	return tracks, nil
+}
+
+func (ps *playStore) MostPlayed(userID string, since time.Time, limit int) ([]models.PlayedTrack, error) {
+	var tracks []models.PlayedTrack
+
+	if err := ps.playedTracks(userID, since).Order("plays DESC, last_played_at DESC").Limit(limit).Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+
+	return tracks, nil
+}
+
+// TrackCounts returns the play counts of the tracks of a playlist that have
+// been played at all.
+func (ps *playStore) TrackCounts(playlistID string) ([]models.TrackPlayCount, error) {
+	var counts []models.TrackPlayCount
+
+	if err := ps.db.Table(ps.table()).
+		Select(
+			"track_id, "+
+				"COUNT(*) FILTER (WHERE event = 'start') AS plays, "+
+				"COUNT(*) FILTER (WHERE event = 'complete') AS completions, "+
+				"COUNT(*) FILTER (WHERE event = 'skip') AS skips",
+		).
+		Where("playlist_id = ?", playlistID).
+		Group("track_id").
+		Find(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+)
+
+type SmartPlaylistStore interface {
+	CreateTable() error
+	Create(playlist models.SmartPlaylistDBModel) error
+	GetOne(whereQuery string, whereArgs ...interface{}) (*models.SmartPlaylistDBModel, error)
+	GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.SmartPlaylistDBModel, error)
+	Delete(whereQuery string, whereArgs ...interface{}) error
+	Tracks(userID string, rule models.SmartRule, limit int) ([]models.TrackDBModel, error)
+	DB() *gorm.DB
+}
+
+type smartPlaylistStore struct {
+	db *gorm.DB
+}
+
+func NewSmartPlaylistStore(db *gorm.DB) SmartPlaylistStore {
+	return &smartPlaylistStore{
+		db: db,
+	}
+}
+
+func (ss *smartPlaylistStore) table() string {
+	return "smart_playlists"
+}
+
+func (ss *smartPlaylistStore) DB() *gorm.DB {
+	return ss.db
+}
+
+func (ss *smartPlaylistStore) CreateTable() error {
+	return ss.db.Table(ss.table()).AutoMigrate(models.SmartPlaylistDBModel{})
+}
+
+func (ss *smartPlaylistStore) Create(playlist models.SmartPlaylistDBModel) error {
+	return ss.db.Table(ss.table()).Create(playlist).Error
+}
+
+func (ss *smartPlaylistStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.SmartPlaylistDBModel, error) {
+	var playlist models.SmartPlaylistDBModel
+	if err := ss.db.Table(ss.table()).Where(whereQuery, whereArgs...).First(&playlist).Error; err != nil {
+		return nil, err
+	}
+
+	return &playlist, nil
+}
+
+func (ss *smartPlaylistStore) GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.SmartPlaylistDBModel, error) {
+	var playlists []models.SmartPlaylistDBModel
+
+	if err := ss.db.Table(ss.table()).Where(whereQuery, whereArgs...).Order(order).Find(&playlists).Error; err != nil {
+		return nil, err
+	}
+
+	return playlists, nil
+}
+
+func (ss *smartPlaylistStore) Delete(whereQuery string, whereArgs ...interface{}) error {
+	return ss.db.Table(ss.table()).Where(whereQuery, whereArgs...).Delete(nil).Error
+}
+
+// Tracks materialises a rule over the library of a user, the playlists they
+// own or have been shared. Plays only count when the user started them.
+func (ss *smartPlaylistStore) Tracks(userID string, rule models.SmartRule, limit int) ([]models.TrackDBModel, error) {
+	var tracks []models.TrackDBModel
+
+	db := ss.db.Table("tracks").
+		Select("tracks.*, tracks.hls_key <> '' AS has_hls").
+		Joins("JOIN playlists ON playlists.playlist_id = tracks.playlist_id").
+		Where("playlists.user_id = ? OR playlists.playlist_id IN (SELECT playlist_id FROM playlist_shares WHERE user_id = ?)", userID, userID)
+
+	since := time.Now().AddDate(0, 0, -rule.Days)
+
+	switch rule.Kind {
+	case models.SmartMostPlayed:
+		db = db.Joins(
+			"JOIN (SELECT track_id, COUNT(*) AS plays FROM plays WHERE user_id = ? AND event = 'start' AND created_at >= ? GROUP BY track_id) p ON p.track_id = tracks.track_id",
+			userID, since,
+		).Order("p.plays DESC, tracks.title")
+
+	case models.SmartRecentlyAdded:
+		db = db.Where("tracks.inserted_at >= ?", since).Order("tracks.inserted_at DESC, tracks.position")
+
+	case models.SmartByArtist:
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(rule.Artist) + "%"
+		db = db.Where("tracks.artists ILIKE ?", pattern).Order("tracks.album, tracks.title")
+
+	case models.SmartNeverPlayed:
+		db = db.Where("NOT EXISTS (SELECT 1 FROM plays WHERE plays.track_id = tracks.track_id AND plays.user_id = ?)", userID).
+			Order("playlists.playlist_name, tracks.position")
+
+	case models.SmartLongestUnplayed:
+		db = db.Joins(
+			"JOIN (SELECT track_id, MAX(created_at) AS last_played_at FROM plays WHERE user_id = ? AND event = 'start' GROUP BY track_id) p ON p.track_id = tracks.track_id",
+			userID,
+		).Order("p.last_played_at, tracks.title")
+
+	default:
+		return nil, fmt.Errorf("unknown smart playlist rule: %s", rule.Kind)
+	}
+
+	if err := db.Limit(limit).Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+
+	return tracks, nil
+}
@@ -2,9 +2,12 @@ package store
 
 import (
 	"errors"
+	"strings"
+	"unicode"
 
 	"github.com/NikhilSharmaWe/playree/playree/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TrackStore interface {
@@ -13,9 +16,16 @@ type TrackStore interface {
 	CreateInBatches(tracks []models.TrackDBModel) error
 	GetOne(whereQuery string, whereArgs ...interface{}) (*models.TrackDBModel, error)
 	GetMany(fields []string, whereQuery string, whereArgs ...interface{}) ([]models.TrackDBModel, error)
+	GetManyOrdered(fields []string, order string, whereQuery string, whereArgs ...interface{}) ([]models.TrackDBModel, error)
 	Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error
 	Delete(whereQuery string, whereArgs ...interface{}) error
 	IsExists(whereQuery string, whereArgs ...interface{}) (bool, error)
+	CountAndSizeByUser(userID string) (int64, int64, error)
+	NextPosition(playlistID string) (int, error)
+	GetPage(fields []string, order string, limit, offset int, whereQuery string, whereArgs ...interface{}) ([]models.TrackDBModel, error)
+	Count(whereQuery string, whereArgs ...interface{}) (int64, error)
+	SearchByUser(userID, query string, limit, offset int) ([]models.TrackDBModel, error)
+	SearchLibrary(userID, query string, limit, offset int) ([]models.TrackSearchResult, error)
 	DB() *gorm.DB
 }
 
@@ -46,7 +56,7 @@ func (ps *trackStore) Create(fr models.TrackDBModel) error {
 }
 
 func (ps *trackStore) CreateInBatches(tracks []models.TrackDBModel) error {
-	return ps.db.Table(ps.table()).Order("track_key").CreateInBatches(tracks, len(tracks)).Error
+	return ps.db.Table(ps.table()).Order("position").CreateInBatches(tracks, len(tracks)).Error
 }
 
 func (ps *trackStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.TrackDBModel, error) {
@@ -68,6 +78,16 @@ func (ps *trackStore) GetMany(fields []string, whereQuery string, whereArgs ...i
 	return tracks, nil
 }
 
+func (ps *trackStore) GetManyOrdered(fields []string, order string, whereQuery string, whereArgs ...interface{}) ([]models.TrackDBModel, error) {
+	var tracks []models.TrackDBModel
+
+	if err := ps.db.Table(ps.table()).Select(fields).Where(whereQuery, whereArgs...).Order(order).Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+
+	return tracks, nil
+}
+
 func (ps *trackStore) Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error {
 	return ps.db.Table(ps.table()).Where(whereQuery, whereArgs...).Updates(updateMap).Error
 }
@@ -95,3 +115,135 @@ func (ps *trackStore) IsExists(whereQuery string, whereArgs ...interface{}) (boo
 
 	return res.IsExists, nil
 }
+
+// CountAndSizeByUser returns the number of tracks and their stored bytes over
+// all playlists of a user.
+func (ps *trackStore) CountAndSizeByUser(userID string) (int64, int64, error) {
+	type Res struct {
+		Tracks      int64
+		StoredBytes int64
+	}
+
+	var res Res
+
+	if err := ps.db.Table(ps.table()).
+		Select("COUNT(*) AS tracks, COALESCE(SUM(tracks.size_bytes), 0) AS stored_bytes").
+		Joins("JOIN playlists ON playlists.playlist_id = tracks.playlist_id").
+		Where("playlists.user_id = ?", userID).
+		Scan(&res).Error; err != nil {
+		return 0, 0, err
+	}
+
+	return res.Tracks, res.StoredBytes, nil
+}
+
+// SearchByUser returns the tracks of a user's own playlists whose title,
+// artists or album match query, best matches first. An empty query matches
+// every track.
+func (ps *trackStore) SearchByUser(userID, query string, limit, offset int) ([]models.TrackDBModel, error) {
+	var tracks []models.TrackDBModel
+
+	db := ps.db.Table(ps.table()).
+		Select("tracks.*").
+		Joins("JOIN playlists ON playlists.playlist_id = tracks.playlist_id").
+		Where("playlists.user_id = ?", userID)
+
+	tsQuery := prefixTSQuery(query)
+	if tsQuery == "" {
+		db = db.Order("tracks.title, tracks.track_id")
+	} else {
+		db = db.Where("tracks.search_vector @@ to_tsquery('simple', ?)", tsQuery).
+			Order(clause.OrderBy{Expression: clause.Expr{
+				SQL:  "ts_rank(tracks.search_vector, to_tsquery('simple', ?)) DESC, tracks.title, tracks.track_id",
+				Vars: []any{tsQuery},
+			}})
+	}
+
+	if err := db.Limit(limit).Offset(offset).Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+
+	return tracks, nil
+}
+
+// SearchLibrary searches the tracks of every playlist a user owns or has been
+// shared, by title, artists, album and playlist name. Title matches rank
+// above artists, album and playlist name matches.
+func (ps *trackStore) SearchLibrary(userID, query string, limit, offset int) ([]models.TrackSearchResult, error) {
+	var results []models.TrackSearchResult
+
+	tsQuery := prefixTSQuery(query)
+	if tsQuery == "" {
+		return results, nil
+	}
+
+	if err := ps.db.Table(ps.table()).
+		Select(
+			"tracks.*, playlists.playlist_name, "+
+				"ts_rank(tracks.search_vector, q.query) + 0.1 * ts_rank(playlists.search_vector, q.query) AS rank",
+		).
+		Joins("JOIN playlists ON playlists.playlist_id = tracks.playlist_id").
+		Joins("CROSS JOIN to_tsquery('simple', ?) AS q(query)", tsQuery).
+		Where("playlists.user_id = ? OR playlists.playlist_id IN (SELECT playlist_id FROM playlist_shares WHERE user_id = ?)", userID, userID).
+		Where("tracks.search_vector @@ q.query OR playlists.search_vector @@ q.query").
+		Order("rank DESC, playlists.playlist_name, tracks.position").
+		Limit(limit).
+		Offset(offset).
+		Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// prefixTSQuery turns free text into a tsquery that matches documents
+// containing every word, the last one as a prefix so results show up while
+// typing. Only letters and digits are kept, so the result is always valid
+// tsquery syntax.
+func prefixTSQuery(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) == 0 {
+		return ""
+	}
+
+	words[len(words)-1] += ":*"
+
+	return strings.Join(words, " & ")
+}
+
+// NextPosition is the position after the last track of a playlist.
+func (ps *trackStore) NextPosition(playlistID string) (int, error) {
+	var next int
+
+	if err := ps.db.Table(ps.table()).
+		Select("COALESCE(MAX(position) + 1, 0)").
+		Where("playlist_id = ?", playlistID).
+		Scan(&next).Error; err != nil {
+		return 0, err
+	}
+
+	return next, nil
+}
+
+func (ps *trackStore) GetPage(fields []string, order string, limit, offset int, whereQuery string, whereArgs ...interface{}) ([]models.TrackDBModel, error) {
+	var tracks []models.TrackDBModel
+
+	if err := ps.db.Table(ps.table()).Select(fields).Where(whereQuery, whereArgs...).Order(order).Limit(limit).Offset(offset).Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+
+	return tracks, nil
+}
+
+func (ps *trackStore) Count(whereQuery string, whereArgs ...interface{}) (int64, error) {
+	var count int64
+
+	if err := ps.db.Table(ps.table()).Where(whereQuery, whereArgs...).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
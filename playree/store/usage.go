@@ -0,0 +1,65 @@
+package store
+
+import (
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UsageStore interface {
+	CreateTable() error
+	GetOne(whereQuery string, whereArgs ...interface{}) (*models.UsageDBModel, error)
+	AddStreamedBytes(userID, period string, n int64) error
+	Delete(whereQuery string, whereArgs ...interface{}) error
+	DB() *gorm.DB
+}
+
+type usageStore struct {
+	db *gorm.DB
+}
+
+func NewUsageStore(db *gorm.DB) UsageStore {
+	return &usageStore{
+		db: db,
+	}
+}
+
+func (us *usageStore) table() string {
+	return "usage"
+}
+
+func (us *usageStore) DB() *gorm.DB {
+	return us.db
+}
+
+func (us *usageStore) CreateTable() error {
+	return us.db.Table(us.table()).AutoMigrate(models.UsageDBModel{})
+}
+
+func (us *usageStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.UsageDBModel, error) {
+	var usage models.UsageDBModel
+	if err := us.db.Table(us.table()).Where(whereQuery, whereArgs...).First(&usage).Error; err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// AddStreamedBytes adds n to the streamed bytes of a user in a period,
+// creating the row on first use.
+func (us *usageStore) AddStreamedBytes(userID, period string, n int64) error {
+	return us.db.Table(us.table()).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "period"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"streamed_bytes": gorm.Expr("usage.streamed_bytes + EXCLUDED.streamed_bytes"),
+		}),
+	}).Create(&models.UsageDBModel{
+		UserID:        userID,
+		Period:        period,
+		StreamedBytes: n,
+	}).Error
+}
+
+func (us *usageStore) Delete(whereQuery string, whereArgs ...interface{}) error {
+	return us.db.Table(us.table()).Where(whereQuery, whereArgs...).Delete(nil).Error
+}
@@ -11,6 +11,7 @@ type UserStore interface {
 	CreateTable() error
 	Create(fr models.UserDBModel) error
 	GetOne(whereQuery string, whereArgs ...interface{}) (*models.UserDBModel, error)
+	GetMany(whereQuery string, whereArgs ...interface{}) ([]models.UserDBModel, error)
 	Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error
 	Delete(whereQuery string, whereArgs ...interface{}) error
 	IsExists(whereQuery string, whereArgs ...interface{}) (bool, error)
@@ -53,6 +54,15 @@ func (us *userStore) GetOne(whereQuery string, whereArgs ...interface{}) (*model
 	return &inventory, nil
 }
 
+func (us *userStore) GetMany(whereQuery string, whereArgs ...interface{}) ([]models.UserDBModel, error) {
+	var users []models.UserDBModel
+	if err := us.db.Table(us.table()).Where(whereQuery, whereArgs...).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
 func (us *userStore) Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error {
 	return us.db.Table(us.table()).Where(whereQuery, whereArgs...).Updates(updateMap).Error
 }
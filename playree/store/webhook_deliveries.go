@@ -0,0 +1,93 @@
+package store
+
+import (
+	"time"
+
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookDeliveryStore interface {
+	CreateTable() error
+	CreateInBatches(deliveries []models.WebhookDeliveryDBModel) error
+	GetPage(order string, limit, offset int, whereQuery string, whereArgs ...interface{}) ([]models.WebhookDeliveryDBModel, error)
+	Claim(limit int, lease time.Duration, whereQuery string, whereArgs ...interface{}) ([]models.WebhookDeliveryDBModel, error)
+	Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error
+	DB() *gorm.DB
+}
+
+type webhookDeliveryStore struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryStore(db *gorm.DB) WebhookDeliveryStore {
+	return &webhookDeliveryStore{
+		db: db,
+	}
+}
+
+func (ds *webhookDeliveryStore) table() string {
+	return "webhook_deliveries"
+}
+
+func (ds *webhookDeliveryStore) DB() *gorm.DB {
+	return ds.db
+}
+
+func (ds *webhookDeliveryStore) CreateTable() error {
+	return ds.db.Table(ds.table()).AutoMigrate(models.WebhookDeliveryDBModel{})
+}
+
+func (ds *webhookDeliveryStore) CreateInBatches(deliveries []models.WebhookDeliveryDBModel) error {
+	return ds.db.Table(ds.table()).CreateInBatches(deliveries, 100).Error
+}
+
+func (ds *webhookDeliveryStore) GetPage(order string, limit, offset int, whereQuery string, whereArgs ...interface{}) ([]models.WebhookDeliveryDBModel, error) {
+	var deliveries []models.WebhookDeliveryDBModel
+
+	if err := ds.db.Table(ds.table()).Where(whereQuery, whereArgs...).Order(order).Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Claim loads up to limit deliveries, oldest next_attempt_at first, and
+// pushes their next_attempt_at back by lease. Rows other instances are
+// claiming at the same time are skipped, so every delivery is sent by one
+// instance; a delivery whose sender died is picked up again after lease.
+func (ds *webhookDeliveryStore) Claim(limit int, lease time.Duration, whereQuery string, whereArgs ...interface{}) ([]models.WebhookDeliveryDBModel, error) {
+	var deliveries []models.WebhookDeliveryDBModel
+
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(ds.table()).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where(whereQuery, whereArgs...).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error; err != nil {
+			return err
+		}
+
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := []string{}
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+
+		return tx.Table(ds.table()).Where("id IN ?", ids).Update("next_attempt_at", time.Now().Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (ds *webhookDeliveryStore) Update(updateMap map[string]any, whereQuery string, whereArgs ...interface{}) error {
+	return ds.db.Table(ds.table()).Where(whereQuery, whereArgs...).Updates(updateMap).Error
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"github.com/NikhilSharmaWe/playree/playree/models"
+	"gorm.io/gorm"
+)
+
+type WebhookStore interface {
+	CreateTable() error
+	Create(webhook models.WebhookDBModel) error
+	GetOne(whereQuery string, whereArgs ...interface{}) (*models.WebhookDBModel, error)
+	GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.WebhookDBModel, error)
+	Delete(whereQuery string, whereArgs ...interface{}) error
+	DB() *gorm.DB
+}
+
+type webhookStore struct {
+	db *gorm.DB
+}
+
+func NewWebhookStore(db *gorm.DB) WebhookStore {
+	return &webhookStore{
+		db: db,
+	}
+}
+
+func (ws *webhookStore) table() string {
+	return "webhooks"
+}
+
+func (ws *webhookStore) DB() *gorm.DB {
+	return ws.db
+}
+
+func (ws *webhookStore) CreateTable() error {
+	return ws.db.Table(ws.table()).AutoMigrate(models.WebhookDBModel{})
+}
+
+func (ws *webhookStore) Create(webhook models.WebhookDBModel) error {
+	return ws.db.Table(ws.table()).Create(webhook).Error
+}
+
+func (ws *webhookStore) GetOne(whereQuery string, whereArgs ...interface{}) (*models.WebhookDBModel, error) {
+	var webhook models.WebhookDBModel
+	if err := ws.db.Table(ws.table()).Where(whereQuery, whereArgs...).First(&webhook).Error; err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (ws *webhookStore) GetManyOrdered(order string, whereQuery string, whereArgs ...interface{}) ([]models.WebhookDBModel, error) {
+	var webhooks []models.WebhookDBModel
+
+	if err := ws.db.Table(ws.table()).Where(whereQuery, whereArgs...).Order(order).Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (ws *webhookStore) Delete(whereQuery string, whereArgs ...interface{}) error {
+	return ws.db.Table(ws.table()).Where(whereQuery, whereArgs...).Delete(nil).Error
+}
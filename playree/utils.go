@@ -2,23 +2,31 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 
 	"github.com/NikhilSharmaWe/playree/playree/app"
 	"github.com/NikhilSharmaWe/playree/playree/models"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// createPlaylistResponseWorkers is how many responses are handled at once,
+// RabbitMQ does not deliver more unacknowledged ones.
+const createPlaylistResponseWorkers = 50
+
 func setupCreatePlaylistSvcRabbitMQForStartup(application *app.Application) (<-chan amqp.Delivery, error) {
 	if err := application.CreatePlaylistResponseClient.CreateBinding(
-		"create-playlist-response-"+application.RabbitMQInstanceID,
-		"create-playlist-response-"+application.RabbitMQInstanceID,
+		app.CreatePlaylistResponseQueue,
+		app.CreatePlaylistResponseQueue,
 		"create-playlist",
 	); err != nil {
 		return nil, err
 	}
 
-	createPlaylistRespMSGBus, err := application.CreatePlaylistResponseClient.Consume("create-playlist-response-"+application.RabbitMQInstanceID, "playree-"+application.RabbitMQInstanceID, false)
+	if err := application.CreatePlaylistResponseClient.ApplyQualtyOfService(createPlaylistResponseWorkers, 0, false); err != nil {
+		return nil, err
+	}
+
+	createPlaylistRespMSGBus, err := application.CreatePlaylistResponseClient.Consume(app.CreatePlaylistResponseQueue, "playree-"+application.RabbitMQInstanceID, false)
 	if err != nil {
 		return nil, err
 	}
@@ -26,17 +34,21 @@ func setupCreatePlaylistSvcRabbitMQForStartup(application *app.Application) (<-c
 	return createPlaylistRespMSGBus, nil
 }
 
-func handleRabbitMQResponses(application *app.Application, msg amqp.Delivery) (*models.RabbitMQCreatePlaylistResponse, error) {
-	response := &models.RabbitMQCreatePlaylistResponse{}
+// handleRabbitMQResponses acknowledges a response once its job is finished
+// in the database. Responses that could not be handled are redelivered,
+// unless they can never be handled.
+func handleRabbitMQResponses(application *app.Application, msg amqp.Delivery) error {
+	response := models.RabbitMQCreatePlaylistResponse{}
 
 	if err := json.Unmarshal(msg.Body, &response); err != nil {
-		return nil, err
+		msg.Nack(false, false)
+		return err
 	}
 
-	_, ok := application.CreatePlaylistResponseChannel[response.PlayreePlaylistID]
-	if !ok {
-		return nil, fmt.Errorf("no create playlist process running with playlist id: %s", response.PlayreePlaylistID)
+	if err := application.HandleCreatePlaylistResponse(response); err != nil {
+		msg.Nack(false, !errors.Is(err, models.ErrJobNotExists))
+		return err
 	}
 
-	return response, nil
+	return msg.Ack(false)
 }